@@ -21,6 +21,16 @@ type ExprVisitor interface {
 	VisitLogicalExpr(expr *LogicalExpr) (any, error)
 	VisitCallExpr(expr *CallExpr) (any, error)
 	VisitLambdaExpr(expr *LambdaExpr) (any, error)
+	VisitListExpr(expr *ListExpr) (any, error)
+	VisitIndexExpr(expr *IndexExpr) (any, error)
+	VisitGetExpr(expr *GetExpr) (any, error)
+	VisitInterpolationExpr(expr *InterpolationExpr) (any, error)
+	VisitCompoundAssignExpr(expr *CompoundAssignExpr) (any, error)
+	VisitIncrementExpr(expr *IncrementExpr) (any, error)
+	VisitConditionalExpr(expr *ConditionalExpr) (any, error)
+	VisitSetIndexExpr(expr *SetIndexExpr) (any, error)
+	VisitSpreadExpr(expr *SpreadExpr) (any, error)
+	VisitAwaitExpr(expr *AwaitExpr) (any, error)
 }
 
 type BinaryExpr struct {
@@ -89,23 +99,156 @@ type CallExpr struct {
 	Callee    Expr
 	Paren     Token
 	Arguments []Expr
+	Named     []NamedArgument // the arguments passed by name, after the others
+}
+
+// NamedArgument is an argument passed by the name of its parameter, like
+// the b of f(1, b: 2).
+type NamedArgument struct {
+	Name  Token
+	Value Expr
+}
+
+// SpreadExpr is an argument whose elements are passed as separate
+// arguments, like the ...args of f(...args).
+type SpreadExpr struct {
+	Dots  Token
+	Value Expr
+}
+
+func (s *SpreadExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitSpreadExpr(s)
 }
 
 func (c *CallExpr) Accept(visitor ExprVisitor) (any, error) {
 	return visitor.VisitCallExpr(c)
 }
 
+// AwaitExpr waits for the future its value evaluates to, like the await of
+// await fetch(url).
+type AwaitExpr struct {
+	Keyword Token
+	Value   Expr
+}
+
+func (a *AwaitExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitAwaitExpr(a)
+}
+
 type LambdaExpr struct {
-	Params []Token
-	Body   []Stmt
+	Keyword Token
+	ParamList
+	Body      []Stmt
+	Generator bool // the body has a yield, so calls return a generator
+}
+
+// ParamList is the parameters of a function or a lambda, like the
+// (a, b = 2, ...rest) of fun f(a, b = 2, ...rest) {}.
+type ParamList struct {
+	Params   []Token
+	Defaults []Expr // the default value of each parameter, nil if it has none
+	Rest     *Token // the parameter collecting the extra arguments, if any
 }
 
 func (l *LambdaExpr) Accept(visitor ExprVisitor) (any, error) {
 	return visitor.VisitLambdaExpr(l)
 }
 
+type ListExpr struct {
+	Bracket  Token
+	Elements []Expr
+}
+
+func (l *ListExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitListExpr(l)
+}
+
+type IndexExpr struct {
+	Object  Expr
+	Bracket Token
+	Index   Expr
+}
+
+func (i *IndexExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitIndexExpr(i)
+}
+
+// SetIndexExpr assigns to an element of a list, like a[i] = value, or
+// updates it, like a[i] += value or a[i]++, evaluating a and i once. Like
+// an IncrementExpr, a ++ or -- has no Value, and its value is the
+// element's after the update if Prefix, or before.
+type SetIndexExpr struct {
+	Object   Expr
+	Bracket  Token
+	Index    Expr
+	Operator Token // "=", "+=", "-=", "*=", "/=", "%=", "++" or "--"
+	Value    Expr
+	Prefix   bool
+}
+
+func (s *SetIndexExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitSetIndexExpr(s)
+}
+
+type GetExpr struct {
+	Object   Expr
+	Name     Token
+	Optional bool // a?.b, which is nil if a is
+}
+
+func (g *GetExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitGetExpr(g)
+}
+
+// InterpolationExpr is a string literal with embedded expressions, like
+// "x = ${x}". The values of its parts are concatenated.
+type InterpolationExpr struct {
+	Parts []Expr
+}
+
+func (i *InterpolationExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitInterpolationExpr(i)
+}
+
+// CompoundAssignExpr is an assignment like x += 1, which applies the
+// operator to the variable and the value.
+type CompoundAssignExpr struct {
+	Name     Token
+	Operator Token // "+=", "-=", "*=", "/=" or "%="
+	Value    Expr
+}
+
+func (c *CompoundAssignExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitCompoundAssignExpr(c)
+}
+
+// IncrementExpr is a ++ or -- applied to a variable, before or after it.
+// Its value is the variable's after the update if Prefix, or before.
+type IncrementExpr struct {
+	Name     Token
+	Operator Token // "++" or "--"
+	Prefix   bool
+}
+
+func (i *IncrementExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitIncrementExpr(i)
+}
+
+// ConditionalExpr is the ternary cond ? a : b.
+type ConditionalExpr struct {
+	Condition Expr
+	Question  Token
+	Then      Expr
+	Else      Expr
+}
+
+func (c *ConditionalExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitConditionalExpr(c)
+}
+
 type Stmt interface {
 	Accept(visitor StmtVisitor) error
+	Line() int
 }
 
 type StmtVisitor interface {
@@ -119,34 +262,54 @@ type StmtVisitor interface {
 	VisitContinueStmt(stmt *ContinueStmt) error
 	VisitFunctionStmt(stmt *FunctionStmt) error
 	VisitReturnStmt(stmt *ReturnStmt) error
+	VisitMatchStmt(stmt *MatchStmt) error
+	VisitYieldStmt(stmt *YieldStmt) error
+	VisitForInStmt(stmt *ForInStmt) error
+	VisitSpawnStmt(stmt *SpawnStmt) error
 }
 
 type ExpressionStmt struct {
-	Expr Expr
+	Start Token // first token of the expression
+	Expr  Expr
 }
 
 func (e *ExpressionStmt) Accept(visitor StmtVisitor) error {
 	return visitor.VisitExpressionStmt(e)
 }
 
+func (e *ExpressionStmt) Line() int {
+	return e.Start.Line
+}
+
 type PrintStmt struct {
-	Expr Expr
+	Keyword Token
+	Expr    Expr
 }
 
 func (p *PrintStmt) Accept(visitor StmtVisitor) error {
 	return visitor.VisitPrintStmt(p)
 }
 
+func (p *PrintStmt) Line() int {
+	return p.Keyword.Line
+}
+
 type VarDeclStmt struct {
 	Name        Token
 	Initializer Expr
+	Const       bool // declared with const, so it can't be reassigned
 }
 
 func (v *VarDeclStmt) Accept(visitor StmtVisitor) error {
 	return visitor.VisitVarDeclStmt(v)
 }
 
+func (v *VarDeclStmt) Line() int {
+	return v.Name.Line
+}
+
 type BlockStmt struct {
+	Start      Token // the opening brace
 	Statements []Stmt
 }
 
@@ -154,7 +317,12 @@ func (b *BlockStmt) Accept(visitor StmtVisitor) error {
 	return visitor.VisitBlockStmt(b)
 }
 
+func (b *BlockStmt) Line() int {
+	return b.Start.Line
+}
+
 type IfStmt struct {
+	Keyword    Token
 	Condition  Expr
 	ThenBranch Stmt
 	ElseBranch Stmt
@@ -164,37 +332,64 @@ func (i *IfStmt) Accept(visitor StmtVisitor) error {
 	return visitor.VisitIfStmt(i)
 }
 
+func (i *IfStmt) Line() int {
+	return i.Keyword.Line
+}
+
 type WhileStmt struct {
+	Keyword   Token // "while" or "for"
 	Condition Expr
 	Body      Stmt
+	Increment Expr // optional, runs after the body even on continue
 }
 
 func (w *WhileStmt) Accept(visitor StmtVisitor) error {
 	return visitor.VisitWhileStmt(w)
 }
 
-type BreakStmt struct{}
+func (w *WhileStmt) Line() int {
+	return w.Keyword.Line
+}
+
+type BreakStmt struct {
+	Keyword Token
+}
 
 func (b *BreakStmt) Accept(visitor StmtVisitor) error {
 	return visitor.VisitBreakStmt(b)
 }
 
-type ContinueStmt struct{}
+func (b *BreakStmt) Line() int {
+	return b.Keyword.Line
+}
+
+type ContinueStmt struct {
+	Keyword Token
+}
 
 func (c *ContinueStmt) Accept(visitor StmtVisitor) error {
 	return visitor.VisitContinueStmt(c)
 }
 
+func (c *ContinueStmt) Line() int {
+	return c.Keyword.Line
+}
+
 type FunctionStmt struct {
-	Name   Token
-	Params []Token
-	Body   []Stmt
+	Name Token
+	ParamList
+	Body      []Stmt
+	Generator bool // the body has a yield, so calls return a generator
 }
 
 func (f *FunctionStmt) Accept(visitor StmtVisitor) error {
 	return visitor.VisitFunctionStmt(f)
 }
 
+func (f *FunctionStmt) Line() int {
+	return f.Name.Line
+}
+
 type ReturnStmt struct {
 	Keyword Token
 	Value   Expr
@@ -203,3 +398,115 @@ type ReturnStmt struct {
 func (r *ReturnStmt) Accept(visitor StmtVisitor) error {
 	return visitor.VisitReturnStmt(r)
 }
+
+func (r *ReturnStmt) Line() int {
+	return r.Keyword.Line
+}
+
+// YieldStmt hands a value to the consumer of a generator and suspends the
+// generator until the consumer asks for the next one.
+type YieldStmt struct {
+	Keyword Token
+	Value   Expr
+}
+
+func (y *YieldStmt) Accept(visitor StmtVisitor) error {
+	return visitor.VisitYieldStmt(y)
+}
+
+func (y *YieldStmt) Line() int {
+	return y.Keyword.Line
+}
+
+// ForInStmt runs its body for each element of a list, character of a
+// string or value of a generator, like for (var x in xs) print x;.
+type ForInStmt struct {
+	Keyword  Token
+	Name     Token
+	Iterable Expr
+	Body     Stmt
+}
+
+func (f *ForInStmt) Accept(visitor StmtVisitor) error {
+	return visitor.VisitForInStmt(f)
+}
+
+func (f *ForInStmt) Line() int {
+	return f.Keyword.Line
+}
+
+// SpawnStmt runs a call on a new fiber, like spawn f(x);. The callee and
+// the arguments are evaluated before the fiber starts.
+type SpawnStmt struct {
+	Keyword Token
+	Call    *CallExpr
+}
+
+func (s *SpawnStmt) Accept(visitor StmtVisitor) error {
+	return visitor.VisitSpawnStmt(s)
+}
+
+func (s *SpawnStmt) Line() int {
+	return s.Keyword.Line
+}
+
+// MatchStmt runs the body of the first arm whose pattern matches the value
+// of the subject and whose guard, if any, is truthy.
+type MatchStmt struct {
+	Keyword Token
+	Subject Expr
+	Arms    []*MatchArm
+}
+
+type MatchArm struct {
+	Case    Token
+	Pattern Pattern
+	Guard   Expr // optional
+	Body    Stmt
+}
+
+func (m *MatchStmt) Accept(visitor StmtVisitor) error {
+	return visitor.VisitMatchStmt(m)
+}
+
+func (m *MatchStmt) Line() int {
+	return m.Keyword.Line
+}
+
+// Pattern is one of the patterns of a match arm.
+type Pattern interface {
+	pattern()
+}
+
+// LiteralPattern matches the values equal to a literal, like 1 or "a".
+type LiteralPattern struct {
+	Token Token
+	Value any
+}
+
+// RangePattern matches the numbers or strings between two literals,
+// inclusive, like 1..9.
+type RangePattern struct {
+	Token Token // the ".."
+	Low   any
+	High  any
+}
+
+// BindingPattern matches any value and binds it to a variable, unless the
+// name is _.
+type BindingPattern struct {
+	Name Token
+}
+
+// ListPattern matches the lists whose elements match its patterns, like
+// [first, _, ...rest]. Without a rest, the lengths must be the same.
+type ListPattern struct {
+	Bracket  Token
+	Elements []Pattern
+	Rest     *Token // the name bound to the remaining elements, if any
+}
+
+func (*LiteralPattern) pattern() {}
+func (*RangePattern) pattern()   {}
+func (*BindingPattern) pattern() {}
+func (*ListPattern) pattern()    {}
@@ -1,10 +1,81 @@
 package glox
 
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
 type Callable interface {
-	Arity() int
+	Arity() Arity
 	Call(interpreter *Interpreter, arguments []any) (any, error)
 }
 
+// Unlimited is the Max of the arities with no upper bound.
+const Unlimited = -1
+
+// Arity is the range of the number of arguments a callable accepts.
+type Arity struct {
+	Min int
+	Max int // or Unlimited
+}
+
+func (a Arity) Accepts(n int) bool {
+	return n >= a.Min && (a.Max == Unlimited || n <= a.Max)
+}
+
+func (a Arity) String() string {
+	switch a.Max {
+	case a.Min:
+		return strconv.Itoa(a.Min)
+	case Unlimited:
+		return fmt.Sprintf("at least %d", a.Min)
+	}
+	return fmt.Sprintf("%d to %d", a.Min, a.Max)
+}
+
+// Arity counts the parameters with no default as required and the others,
+// unless there's a rest parameter, as the optional ones.
+func (p ParamList) Arity() Arity {
+	required := 0
+	for required < len(p.Defaults) && p.Defaults[required] == nil {
+		required++
+	}
+	if p.Rest != nil {
+		return Arity{required, Unlimited}
+	}
+	return Arity{required, len(p.Params)}
+}
+
+// noArgument fills the position of the parameters skipped by the named
+// arguments of a call, so they get their default value.
+type noArgument struct{}
+
+// bind defines the parameters in env. The parameters with no argument get
+// their default value, evaluated in env, so it can refer to the parameters
+// before them.
+func (p ParamList) bind(interpreter *Interpreter, env Env, arguments []any) error {
+	for i, param := range p.Params {
+		if i < len(arguments) && arguments[i] != (noArgument{}) {
+			env.Define(param.Lexeme, arguments[i])
+			continue
+		}
+		value, err := interpreter.evaluateIn(p.Defaults[i], env)
+		if err != nil {
+			return err
+		}
+		env.Define(param.Lexeme, value)
+	}
+	if p.Rest != nil {
+		var rest []any
+		if len(arguments) > len(p.Params) {
+			rest = slices.Clone(arguments[len(p.Params):])
+		}
+		env.Define(p.Rest.Lexeme, NewList(rest))
+	}
+	return nil
+}
+
 type Function struct {
 	closure Env
 	stmt    *FunctionStmt
@@ -14,8 +85,8 @@ func NewFunction(closure Env, stmt *FunctionStmt) Callable {
 	return &Function{closure, stmt}
 }
 
-func (f *Function) Arity() int {
-	return len(f.stmt.Params)
+func (f *Function) Arity() Arity {
+	return f.stmt.Arity()
 }
 
 func (f *Function) String() string {
@@ -23,18 +94,7 @@ func (f *Function) String() string {
 }
 
 func (f *Function) Call(interpreter *Interpreter, arguments []any) (any, error) {
-	env := NewEnvironment(f.closure)
-	for i, param := range f.stmt.Params {
-		env.Define(param.Lexeme, arguments[i])
-	}
-
-	if err := interpreter.ExecuteBlock(f.stmt.Body, env); err != nil {
-		if returnValue, ok := err.(*ReturnValue); ok {
-			return returnValue.Value, nil
-		}
-		return nil, err
-	}
-	return nil, nil
+	return callFunction(interpreter, f, arguments)
 }
 
 type Lambda struct {
@@ -46,8 +106,8 @@ func NewLambda(closure Env, expr *LambdaExpr) Callable {
 	return &Lambda{closure, expr}
 }
 
-func (l *Lambda) Arity() int {
-	return len(l.expr.Params)
+func (l *Lambda) Arity() Arity {
+	return l.expr.Arity()
 }
 
 func (l *Lambda) String() string {
@@ -55,33 +115,83 @@ func (l *Lambda) String() string {
 }
 
 func (l *Lambda) Call(interpreter *Interpreter, arguments []any) (any, error) {
-	env := NewEnvironment(l.closure)
-	for i, param := range l.expr.Params {
-		env.Define(param.Lexeme, arguments[i])
-	}
+	return callFunction(interpreter, l, arguments)
+}
+
+// callFunction runs the body of a function or lambda. A return of a call
+// hands the call back as a *TailCall instead of making it, and when the
+// callee is a function or lambda too, callFunction loops to run its body in
+// place of the caller's, so tail recursion doesn't grow the Go stack, be it
+// direct or mutual. Any other callee is a native, bound methods included,
+// which has no body to loop into, so it's called as usual.
+func callFunction(interpreter *Interpreter, callable Callable, arguments []any) (any, error) {
+	for {
+		var (
+			closure   Env
+			params    ParamList
+			body      []Stmt
+			generator bool
+			name      = "lambda"
+		)
+		switch callable := callable.(type) {
+		case *Function:
+			closure, params, body = callable.closure, callable.stmt.ParamList, callable.stmt.Body
+			generator, name = callable.stmt.Generator, callable.stmt.Name.Lexeme
+		case *Lambda:
+			closure, params, body = callable.closure, callable.expr.ParamList, callable.expr.Body
+			generator = callable.expr.Generator
+		}
+
+		env := NewEnvironment(closure)
+		if err := params.bind(interpreter, env, arguments); err != nil {
+			return nil, err
+		}
+		if generator {
+			return NewGenerator(name, func(interpreter *Interpreter) error {
+				return interpreter.ExecuteBlock(body, env)
+			}), nil
+		}
 
-	if err := interpreter.ExecuteBlock(l.expr.Body, env); err != nil {
-		if returnValue, ok := err.(*ReturnValue); ok {
-			return returnValue.Value, nil
+		err := interpreter.ExecuteBlock(body, env)
+		switch err := err.(type) {
+		case nil:
+			return nil, nil
+		case *ReturnValue:
+			return err.Value, nil
+		case *TailCall:
+			switch err.Callable.(type) {
+			case *Function, *Lambda:
+				interpreter.Profiler.TailCall(err.Callable)
+				callable, arguments = err.Callable, err.Arguments
+			default:
+				return interpreter.invoke(err.Callable, err.Arguments, err.Paren)
+			}
+		default:
+			return nil, err
 		}
-		return nil, err
 	}
-	return nil, nil
 }
 
 type NativeFunctionHandler func(*Interpreter, []any) (any, error)
 
 type NativeFunction struct {
 	name    string
-	arity   int
+	arity   Arity
 	handler NativeFunctionHandler
 }
 
 func NewNativeFunction(name string, arity int, handler NativeFunctionHandler) Callable {
-	return &NativeFunction{name, arity, handler}
+	return &NativeFunction{name, Arity{arity, arity}, handler}
 }
 
-func (n *NativeFunction) Arity() int {
+// NewVariadicNativeFunction creates a native taking from min to max
+// arguments, or at least min if max is Unlimited. The handler gets the
+// arguments of the call as they are, so it must check how many there are.
+func NewVariadicNativeFunction(name string, min, max int, handler NativeFunctionHandler) Callable {
+	return &NativeFunction{name, Arity{min, max}, handler}
+}
+
+func (n *NativeFunction) Arity() Arity {
 	return n.arity
 }
 
@@ -92,3 +202,54 @@ func (n *NativeFunction) String() string {
 func (n *NativeFunction) Call(interpreter *Interpreter, arguments []any) (any, error) {
 	return n.handler(interpreter, arguments)
 }
+
+// BoundMethod is a native looked up as a method of a value, like s.upper().
+// The value is passed to the native as its first argument.
+type BoundMethod struct {
+	receiver any
+	method   Callable
+}
+
+func NewBoundMethod(receiver any, method Callable) Callable {
+	return &BoundMethod{receiver, method}
+}
+
+func (b *BoundMethod) Arity() Arity {
+	arity := b.method.Arity()
+	arity.Min = max(arity.Min-1, 0)
+	if arity.Max != Unlimited {
+		arity.Max--
+	}
+	return arity
+}
+
+func (b *BoundMethod) String() string {
+	return fmt.Sprint(b.method)
+}
+
+func (b *BoundMethod) Call(interpreter *Interpreter, arguments []any) (any, error) {
+	return b.method.Call(interpreter, append([]any{b.receiver}, arguments...))
+}
+
+func stringArg(native string, arg any) (string, error) {
+	s, ok := arg.(string)
+	if !ok {
+		return "", fmt.Errorf("%s expects a string, got %T", native, arg)
+	}
+	return s, nil
+}
+
+func numberArg(native string, arg any) (float64, error) {
+	if !isNumber(arg) {
+		return 0, fmt.Errorf("%s expects a number, got %T", native, arg)
+	}
+	return toFloat(arg), nil
+}
+
+func integerArg(native string, arg any) (int64, error) {
+	x, ok := toInteger(arg)
+	if !ok {
+		return 0, fmt.Errorf("%s expects an integer, got %s", native, stringify(arg))
+	}
+	return x, nil
+}
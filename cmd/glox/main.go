@@ -1,7 +1,10 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/tangzero/glox"
@@ -9,27 +12,224 @@ import (
 
 // exit codes from https://man.freebsd.org/cgi/man.cgi?query=sysexits
 const (
+	ExitFailure  = 1
 	ExitUsage    = 64
+	ExitDataErr  = 65
 	ExitSoftware = 70
 )
 
+const usage = `Usage: glox [script | -] [args...]
+       glox -e source [args...]
+       glox run (script | -) [args...]
+       glox eval source [args...]
+       glox repl
+       glox check script...
+       glox test [-format human|junit|tap] [path...]
+       glox cover [-lcov file] [-html file] script...
+       glox profile [-o file] [-rate duration] script [args...]
+
+A script given as - is read from the standard input. The args that follow
+the script are available to it through the args global.
+`
+
 func main() {
-	if len(os.Args) > 2 {
-		fmt.Fprintln(os.Stderr, "Usage: glox [script]")
+	if len(os.Args) == 1 {
+		repl(nil)
+		return
+	}
+
+	command, args := os.Args[1], os.Args[2:]
+	switch command {
+	case "run":
+		run(args)
+	case "eval", "-e":
+		eval(args)
+	case "repl":
+		repl(args)
+	case "check":
+		check(args)
+	case "test":
+		test(args)
+	case "cover":
+		cover(args)
+	case "profile":
+		profile(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		run(os.Args[1:])
+	}
+}
+
+func exitUsage() {
+	fmt.Fprint(os.Stderr, usage)
+	os.Exit(ExitUsage)
+}
+
+func run(args []string) {
+	if len(args) == 0 {
+		exitUsage()
+	}
+	script, args := args[0], args[1:]
+
+	var err error
+	if script == "-" {
+		var source []byte
+		source, err = io.ReadAll(os.Stdin)
+		if err == nil {
+			err = glox.Run(string(source), args...)
+		}
+	} else {
+		err = glox.RunFile(script, args...)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func eval(args []string) {
+	if len(args) == 0 {
+		exitUsage()
+	}
+	if err := glox.Run(args[0], args[1:]...); err != nil {
+		fail(err)
+	}
+}
+
+func repl(args []string) {
+	if len(args) != 0 {
+		exitUsage()
+	}
+	if err := glox.RunPrompt(); err != nil {
+		fail(err)
+	}
+}
+
+// fail exits with the status the script asked for with exit(), or reports
+// the error.
+func fail(err error) {
+	var exit *glox.Exit
+	if errors.As(err, &exit) {
+		os.Exit(exit.Code)
+	}
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(ExitSoftware)
+}
+
+func check(args []string) {
+	if len(args) == 0 {
+		exitUsage()
+	}
+	failed := false
+	for _, script := range args {
+		if err := glox.CheckFile(script); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", script, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(ExitDataErr)
+	}
+}
+
+func test(args []string) {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	format := flags.String("format", glox.ReportHuman, "report format: human, junit or tap")
+	flags.Parse(args)
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	suites, err := glox.RunTests(paths...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(ExitSoftware)
+	}
+	if err := glox.WriteTestReport(os.Stdout, *format, suites); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(ExitUsage)
 	}
+	for _, suite := range suites {
+		if !suite.Passed() {
+			os.Exit(ExitFailure)
+		}
+	}
+}
 
-	if len(os.Args) == 2 {
-		script := os.Args[1]
-		if err := glox.RunFile(script); err != nil {
+func cover(args []string) {
+	flags := flag.NewFlagSet("cover", flag.ExitOnError)
+	lcov := flags.String("lcov", "lcov.info", "write the LCOV report to `file`")
+	html := flags.String("html", "", "write the annotated HTML report to `file`")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		exitUsage()
+	}
+
+	failed := false
+	var coverages []*glox.Coverage
+	for _, script := range flags.Args() {
+		coverage, err := glox.CoverFile(script)
+		if exit := (*glox.Exit)(nil); errors.As(err, &exit) {
+			failed = failed || exit.Code != 0
+		} else if err != nil {
 			fmt.Fprintln(os.Stderr, err)
-			os.Exit(ExitSoftware)
+			failed = true
+		}
+		if coverage != nil {
+			coverages = append(coverages, coverage)
 		}
-		return // success
 	}
 
-	if err := glox.RunPrompt(); err != nil {
+	if err := writeReport(*lcov, func(w io.Writer) error { return glox.WriteLCOV(w, coverages...) }); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(ExitSoftware)
+	}
+	if err := writeReport(*html, func(w io.Writer) error { return glox.WriteCoverageHTML(w, coverages...) }); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(ExitSoftware)
 	}
+	if failed {
+		os.Exit(ExitSoftware)
+	}
+}
+
+func profile(args []string) {
+	flags := flag.NewFlagSet("profile", flag.ExitOnError)
+	output := flags.String("o", "lox.pprof", "write the pprof profile to `file`")
+	rate := flags.Duration("rate", glox.DefaultProfileRate, "sampling interval")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		exitUsage()
+	}
+
+	profiler, err := glox.ProfileFile(flags.Arg(0), *rate, flags.Args()[1:]...)
+	if profiler == nil {
+		fail(err)
+	}
+	profiler.WriteSummary(os.Stderr)
+	if err := writeReport(*output, profiler.WriteProfile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(ExitSoftware)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func writeReport(path string, write func(io.Writer) error) error {
+	if path == "" {
+		return nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// TestMain runs glox itself instead of the tests when asked to by
+// runGlox, so the tests can check the exit status of the process.
+func TestMain(m *testing.M) {
+	if os.Getenv("GLOX_RUN_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func runGlox(t *testing.T, args ...string) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "GLOX_RUN_MAIN=1")
+	err := cmd.Run()
+	if exit := (*exec.ExitError)(nil); errors.As(err, &exit) {
+		return exit.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return 0
+}
+
+func TestExitStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		status int
+	}{
+		{"success", []string{"-e", "print 1;"}, 0},
+		{"exit", []string{"-e", "exit(3);"}, 3},
+		{"exit zero", []string{"-e", "exit(0); print nil + 1;"}, 0},
+		{"exit in a function", []string{"-e", "fun f() { exit(4); } f();"}, 4},
+		{"exit in a fiber", []string{"-e", "var c = channel(); fun f() { exit(5); } spawn f(); c.receive();"}, 5},
+		{"exit while awaiting", []string{"-e", "fun f() { exit(6); } spawn f(); await sleep(60000);"}, 6},
+		{"exit while running", []string{"-e", "fun f() { exit(7); } spawn f(); while (true) {}"}, 7},
+		{"runtime error", []string{"-e", "print nil + 1;"}, ExitSoftware},
+		{"syntax error", []string{"-e", "print ;"}, ExitSoftware},
+		{"check", []string{"check", "../../testdata/conformance/variables/missing_name.lox"}, ExitDataErr},
+		{"usage", []string{"run"}, ExitUsage},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if status := runGlox(t, test.args...); status != test.status {
+				t.Errorf("expected status %d, got %d", test.status, status)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package glox_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/tangzero/glox"
+)
+
+// The conformance corpus lives in testdata/conformance, one directory per
+// chapter of the book. Each script documents its own expectations with
+// upstream-style annotations:
+//
+//	print 1; // expect: 1
+//	-"a";    // expect runtime error: operand must be a number, got string.
+//	var = 1; // Error at '=': expect variable name
+//	// [line 3] Error at end: expect ';' after value
+const conformanceDir = "testdata/conformance"
+
+// The upstream corpus, the test directory of a checkout of
+// https://github.com/munificent/craftinginterpreters, runs too when
+// LOX_TEST_SUITE points at it. glox words its errors differently, so only
+// the lines of the errors are compared. upstreamSkips lists the directories
+// and scripts of the corpus that can't pass, and why; scripts elsewhere
+// that use classes are skipped too.
+var upstreamSkips = map[string]string{
+	"benchmark":   "benchmarks, not tests",
+	"expressions": "tests the syntax tree printer of the parsing chapter",
+	"scanning":    "tests the token dump of the scanning chapter",
+	"limit":       "tests the limits of clox",
+
+	"class":       "classes are not implemented",
+	"constructor": "classes are not implemented",
+	"field":       "classes are not implemented",
+	"inheritance": "classes are not implemented",
+	"method":      "classes are not implemented",
+	"super":       "classes are not implemented",
+	"this":        "classes are not implemented",
+
+	"number/literals.lox":          "-0 is the integer 0, which prints as 0",
+	"function/print.lox":           "natives print with their name, like <native fn clock>",
+	"operator/add_bool_string.lox": "+ concatenates a string with any value",
+	"operator/add_string_nil.lox":  "+ concatenates a string with any value",
+	"unexpected_character.lox":     "| is the bitwise or operator",
+}
+
+var (
+	expectOutput       = regexp.MustCompile(`// expect: ?(.*)`)
+	expectRuntimeError = regexp.MustCompile(`// expect runtime error: (.+)`)
+	// upstream annotates the errors only jlox reports with [java line N],
+	// and the ones only clox reports with [c line N]
+	expectError = regexp.MustCompile(`// (\[(?:java )?line (\d+)\] )?(Error.*)`)
+	errorLine   = regexp.MustCompile(`^\[line \d+\]`)
+	usesClasses = regexp.MustCompile(`\b(class|this|super)\b`)
+	comment     = regexp.MustCompile(`//.*`)
+)
+
+type expectations struct {
+	output []string
+	err    string
+}
+
+func TestConformance(t *testing.T) {
+	chapters, err := os.ReadDir(conformanceDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chapter := range chapters {
+		t.Run(chapter.Name(), func(t *testing.T) {
+			scripts, err := filepath.Glob(filepath.Join(conformanceDir, chapter.Name(), "*.lox"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, script := range scripts {
+				t.Run(strings.TrimSuffix(filepath.Base(script), ".lox"), func(t *testing.T) {
+					runConformanceScript(t, script, false)
+				})
+			}
+		})
+	}
+}
+
+func TestUpstreamConformance(t *testing.T) {
+	root := os.Getenv("LOX_TEST_SUITE")
+	if root == "" {
+		t.Skip("set LOX_TEST_SUITE to the test directory of a craftinginterpreters checkout")
+	}
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return err
+		}
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		if reason, ok := upstreamSkips[name]; ok {
+			t.Run(name, func(t *testing.T) { t.Skip(reason) })
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || filepath.Ext(path) != ".lox" {
+			return nil
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		t.Run(name, func(t *testing.T) {
+			if usesClasses.Match(comment.ReplaceAll(source, nil)) {
+				t.Skip("classes are not implemented")
+			}
+			runConformanceScript(t, path, true)
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runConformanceScript runs script and checks its output and error against
+// its annotations. With linesOnly, only the lines of the errors are
+// compared, not their messages.
+func runConformanceScript(t *testing.T, script string, linesOnly bool) {
+	expected, err := parseExpectations(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := captureStdout(func() error { return glox.RunFile(script) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []string
+	if output.text != "" {
+		actual = strings.Split(strings.TrimSuffix(output.text, "\n"), "\n")
+	}
+	for i := range max(len(expected.output), len(actual)) {
+		switch {
+		case i >= len(actual):
+			t.Errorf("missing expected output %q", expected.output[i])
+		case i >= len(expected.output):
+			t.Errorf("unexpected output %q", actual[i])
+		case actual[i] != expected.output[i]:
+			t.Errorf("expected output %q, got %q", expected.output[i], actual[i])
+		}
+	}
+
+	switch {
+	case output.err == nil && expected.err != "":
+		t.Errorf("expected error %q, got none", expected.err)
+	case output.err != nil && expected.err == "":
+		t.Errorf("unexpected error %q", output.err)
+	case output.err != nil && linesOnly:
+		if errorLine.FindString(output.err.Error()) != errorLine.FindString(expected.err) {
+			t.Errorf("expected error %q, got %q", expected.err, output.err)
+		}
+	case output.err != nil && output.err.Error() != expected.err:
+		t.Errorf("expected error %q, got %q", expected.err, output.err)
+	}
+}
+
+func parseExpectations(script string) (*expectations, error) {
+	file, err := os.Open(script)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// glox stops at the first error, so only the first one is expected
+	expected := &expectations{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if match := expectOutput.FindStringSubmatch(text); match != nil {
+			expected.output = append(expected.output, match[1])
+		} else if expected.err != "" {
+			continue
+		} else if match := expectRuntimeError.FindStringSubmatch(text); match != nil {
+			expected.err = glox.Error(line, match[1]).Error()
+		} else if match := expectError.FindStringSubmatch(text); match != nil {
+			if match[2] != "" {
+				expected.err = fmt.Sprintf("[line %s] %s", match[2], match[3])
+			} else {
+				expected.err = fmt.Sprintf("[line %d] %s", line, match[3])
+			}
+		}
+	}
+	return expected, scanner.Err()
+}
+
+type captured struct {
+	text string
+	err  error
+}
+
+// captureStdout runs fn with os.Stdout redirected, so it must not be used
+// from parallel tests.
+func captureStdout(fn func() error) (*captured, error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	text := make(chan string)
+	go func() {
+		bytes, _ := io.ReadAll(reader)
+		text <- string(bytes)
+	}()
+	err = fn()
+	writer.Close()
+	return &captured{text: <-text, err: err}, nil
+}
@@ -0,0 +1,263 @@
+package glox
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Coverage records which statements and branches of a program ran. All the
+// recording methods are no-ops on a nil *Coverage, so the interpreter can
+// call them unconditionally. Fibers the program abandoned may still record,
+// so read Lines and Branches through the methods and the report writers,
+// which lock the coverage.
+type Coverage struct {
+	File     string
+	Source   string
+	Lines    map[int]int // line -> number of statements executed on it
+	Branches []*Branch
+
+	mu sync.Mutex // fibers record concurrently
+
+	branches map[any]*Branch
+}
+
+// Branch is a two-way decision point: the condition of an if statement or
+// loop, the short-circuit of a logical expression, or whether a match arm
+// matches.
+type Branch struct {
+	Line  int
+	Kind  string // "if", "while", "for", "and", "or", "??", "?:" or "case"
+	Taken [2]int // [0] counts true/evaluated-right, [1] false/short-circuited
+}
+
+// NewCoverage registers every statement and branch point of the program, so
+// code that never runs is still reported.
+func NewCoverage(file, source string, program Program) *Coverage {
+	c := &Coverage{
+		File:     file,
+		Source:   source,
+		Lines:    make(map[int]int),
+		branches: make(map[any]*Branch),
+	}
+	Walk(program, func(node any) bool {
+		if stmt, ok := node.(Stmt); ok && coverable(stmt) {
+			c.Lines[stmt.Line()] += 0
+		}
+		switch node := node.(type) {
+		case *IfStmt:
+			c.addBranch(node, node.Line(), "if")
+		case *WhileStmt:
+			c.addBranch(node, node.Line(), node.Keyword.Lexeme)
+		case *ForInStmt:
+			c.addBranch(node, node.Line(), "for")
+		case *LogicalExpr:
+			c.addBranch(node, node.Operator.Line, node.Operator.Lexeme)
+		case *ConditionalExpr:
+			c.addBranch(node, node.Question.Line, "?:")
+		case *MatchStmt:
+			for _, arm := range node.Arms {
+				c.addBranch(arm, arm.Case.Line, "case")
+			}
+		}
+		return true
+	})
+	return c
+}
+
+func (c *Coverage) addBranch(node any, line int, kind string) {
+	branch := &Branch{Line: line, Kind: kind}
+	c.branches[node] = branch
+	c.Branches = append(c.Branches, branch)
+}
+
+// blocks only group statements, they don't run anything by themselves.
+func coverable(stmt Stmt) bool {
+	_, ok := stmt.(*BlockStmt)
+	return !ok
+}
+
+func (c *Coverage) Statement(stmt Stmt) {
+	if c == nil || !coverable(stmt) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Lines[stmt.Line()]++
+}
+
+// Branch records the outcome of a branch point registered by NewCoverage.
+func (c *Coverage) Branch(node any, taken bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if branch, ok := c.branches[node]; ok {
+		if taken {
+			branch.Taken[0]++
+		} else {
+			branch.Taken[1]++
+		}
+	}
+}
+
+func (c *Coverage) LinesHit() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.linesHit()
+}
+
+func (c *Coverage) linesHit() int {
+	hit := 0
+	for _, count := range c.Lines {
+		if count > 0 {
+			hit++
+		}
+	}
+	return hit
+}
+
+func (c *Coverage) BranchesHit() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.branchesHit()
+}
+
+func (c *Coverage) branchesHit() int {
+	hit := 0
+	for _, branch := range c.Branches {
+		for _, count := range branch.Taken {
+			if count > 0 {
+				hit++
+			}
+		}
+	}
+	return hit
+}
+
+// WriteLCOV writes the coverage in the LCOV tracefile format understood by
+// genhtml and most CI coverage services.
+func WriteLCOV(w io.Writer, coverages ...*Coverage) error {
+	for _, c := range coverages {
+		if err := c.writeLCOV(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Coverage) writeLCOV(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintln(w, "TN:")
+	fmt.Fprintf(w, "SF:%s\n", c.File)
+	blocks := make(map[int]int) // line -> branch points seen on it
+	for _, branch := range c.Branches {
+		block := blocks[branch.Line]
+		blocks[branch.Line]++
+		for i, count := range branch.Taken {
+			taken := fmt.Sprint(count)
+			if c.Lines[branch.Line] == 0 {
+				taken = "-" // the branch point itself never ran
+			}
+			fmt.Fprintf(w, "BRDA:%d,%d,%d,%s\n", branch.Line, block, i, taken)
+		}
+	}
+	fmt.Fprintf(w, "BRF:%d\n", 2*len(c.Branches))
+	fmt.Fprintf(w, "BRH:%d\n", c.branchesHit())
+	for _, line := range slices.Sorted(maps.Keys(c.Lines)) {
+		fmt.Fprintf(w, "DA:%d,%d\n", line, c.Lines[line])
+	}
+	fmt.Fprintf(w, "LF:%d\n", len(c.Lines))
+	fmt.Fprintf(w, "LH:%d\n", c.linesHit())
+	_, err := fmt.Fprintln(w, "end_of_record")
+	return err
+}
+
+type coverageLine struct {
+	Number int
+	Source string
+	Hits   string
+	Class  string // "covered", "partial", "uncovered" or empty
+	Title  string
+}
+
+type coverageFile struct {
+	File    string
+	Percent string
+	Lines   []coverageLine
+}
+
+var coverageTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>glox coverage</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; font-family: monospace; }
+td { padding: 0 8px; white-space: pre; }
+td.number, td.hits { color: #888; text-align: right; }
+tr.covered { background: #dfd; }
+tr.partial { background: #ffd; }
+tr.uncovered { background: #fdd; }
+</style>
+</head>
+<body>
+{{range .}}
+<h2>{{.File}} ({{.Percent}} of lines)</h2>
+<table>
+{{range .Lines}}<tr class="{{.Class}}" title="{{.Title}}"><td class="number">{{.Number}}</td><td class="hits">{{.Hits}}</td><td>{{.Source}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteCoverageHTML writes the sources annotated with their line and branch
+// coverage.
+func WriteCoverageHTML(w io.Writer, coverages ...*Coverage) error {
+	var files []coverageFile
+	for _, c := range coverages {
+		files = append(files, c.htmlFile())
+	}
+	return coverageTemplate.Execute(w, files)
+}
+
+func (c *Coverage) htmlFile() coverageFile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	file := coverageFile{File: c.File, Percent: "100%"}
+	if len(c.Lines) > 0 {
+		file.Percent = fmt.Sprintf("%.1f%%", 100*float64(c.linesHit())/float64(len(c.Lines)))
+	}
+	branches := make(map[int][]*Branch)
+	for _, branch := range c.Branches {
+		branches[branch.Line] = append(branches[branch.Line], branch)
+	}
+	for i, source := range strings.Split(c.Source, "\n") {
+		line := coverageLine{Number: i + 1, Source: source}
+		if hits, ok := c.Lines[line.Number]; ok {
+			line.Hits = fmt.Sprint(hits)
+			line.Class = "uncovered"
+			if hits > 0 {
+				line.Class = "covered"
+			}
+		}
+		var titles []string
+		for _, branch := range branches[line.Number] {
+			titles = append(titles, fmt.Sprintf("%s: %d/%d", branch.Kind, branch.Taken[0], branch.Taken[1]))
+			if line.Class == "covered" && (branch.Taken[0] == 0 || branch.Taken[1] == 0) {
+				line.Class = "partial"
+			}
+		}
+		line.Title = strings.Join(titles, ", ")
+		file.Lines = append(file.Lines, line)
+	}
+	return file
+}
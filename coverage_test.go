@@ -0,0 +1,52 @@
+package glox_test
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/tangzero/glox"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the tests")
+
+func TestCoverageReports(t *testing.T) {
+	coverage, err := glox.CoverFile("testdata/coverage/sample.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports := map[string]func(io.Writer, ...*glox.Coverage) error{
+		"testdata/coverage/sample.lcov": glox.WriteLCOV,
+		"testdata/coverage/sample.html": glox.WriteCoverageHTML,
+	}
+	for golden, write := range reports {
+		t.Run(golden, func(t *testing.T) {
+			var report bytes.Buffer
+			if err := write(&report, coverage); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, golden, report.Bytes())
+		})
+	}
+}
+
+// checkGolden compares actual with the golden file, or rewrites the file
+// when the tests run with -update.
+func checkGolden(t *testing.T, golden string, actual []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(golden, actual, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("%s doesn't match, expected:\n%s\ngot:\n%s", golden, expected, actual)
+	}
+}
@@ -0,0 +1,163 @@
+package glox
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// DivisionScale is the number of decimal places a decimal with no exact
+// decimal representation, like decimal("1") / 3, is printed with.
+const DivisionScale = 20
+
+// maxPowerBits bounds the size of the powers of big integers and decimals,
+// in bits, and the number of decimal places of the powers of decimals, so
+// a huge exponent is a runtime error instead of computing for hours.
+const maxPowerBits = 1 << 24
+
+// checkPower fails if x ** n could have more than maxPowerBits bits.
+func checkPower(x, n *big.Int) error {
+	if x.CmpAbs(big.NewInt(1)) <= 0 {
+		return nil // 0, 1 and -1 stay small
+	}
+	bits := new(big.Int).Mul(big.NewInt(int64(x.BitLen())), new(big.Int).Abs(n))
+	if bits.Cmp(big.NewInt(maxPowerBits)) > 0 {
+		return fmt.Errorf("exponent %s is too large, the result would have over %d bits", n, maxPowerBits)
+	}
+	return nil
+}
+
+// Decimal is an exact decimal number, like decimal("0.10"). Its value is
+// kept as a fraction, so even the results of divisions stay exact, and its
+// scale is the number of decimal places it's printed with.
+type Decimal struct {
+	rat   *big.Rat
+	scale int
+}
+
+// ParseDecimal parses a decimal number like "-12.50"; the scale of the
+// result is the number of digits after its point.
+func ParseDecimal(text string) (Decimal, error) {
+	rat, ok := new(big.Rat).SetString(text)
+	if !ok || strings.ContainsAny(text, "/eE") {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", text)
+	}
+	scale := 0
+	if _, fraction, ok := strings.Cut(text, "."); ok {
+		scale = len(fraction)
+	}
+	return Decimal{rat, scale}, nil
+}
+
+func NewDecimal(rat *big.Rat, scale int) Decimal {
+	if digits, exact := rat.FloatPrec(); !exact {
+		scale = max(scale, DivisionScale)
+	} else {
+		scale = max(scale, digits)
+	}
+	return Decimal{rat, scale}
+}
+
+func (d Decimal) Rat() *big.Rat {
+	return d.rat
+}
+
+func (d Decimal) String() string {
+	return d.rat.FloatString(d.scale)
+}
+
+// toBig converts an integer or a big integer to a big integer.
+func toBig(value any) *big.Int {
+	if x, ok := value.(*big.Int); ok {
+		return x
+	}
+	return big.NewInt(value.(int64))
+}
+
+// toDecimal converts an integer, a big integer or a decimal to a decimal.
+func toDecimal(value any) Decimal {
+	switch x := value.(type) {
+	case Decimal:
+		return x
+	case *big.Int:
+		return Decimal{new(big.Rat).SetInt(x), 0}
+	default:
+		return Decimal{new(big.Rat).SetInt64(x.(int64)), 0}
+	}
+}
+
+func bigArithmetic(operator TokenType, x, y *big.Int) (any, error) {
+	switch operator {
+	case Plus:
+		return new(big.Int).Add(x, y), nil
+	case Minus:
+		return new(big.Int).Sub(x, y), nil
+	case Star:
+		return new(big.Int).Mul(x, y), nil
+	case Percent:
+		if y.Sign() == 0 {
+			return nil, fmt.Errorf("integer modulo by zero")
+		}
+		return new(big.Int).Rem(x, y), nil
+	case StarStar:
+		if y.Sign() >= 0 {
+			if err := checkPower(x, y); err != nil {
+				return nil, err
+			}
+			return new(big.Int).Exp(x, y, nil), nil
+		}
+	}
+	// like integers, big integers divide into fractions
+	return decimalArithmetic(operator, toDecimal(x), toDecimal(y))
+}
+
+func decimalArithmetic(operator TokenType, x, y Decimal) (any, error) {
+	switch operator {
+	case Plus:
+		return NewDecimal(new(big.Rat).Add(x.rat, y.rat), max(x.scale, y.scale)), nil
+	case Minus:
+		return NewDecimal(new(big.Rat).Sub(x.rat, y.rat), max(x.scale, y.scale)), nil
+	case Star:
+		return NewDecimal(new(big.Rat).Mul(x.rat, y.rat), x.scale+y.scale), nil
+	case StarStar:
+		n, ok := toInteger(y)
+		if !ok {
+			return nil, fmt.Errorf("decimal exponent must be an integer, got %s", y)
+		}
+		if x.rat.Sign() == 0 && n < 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		exponent := big.NewInt(n)
+		if err := checkPower(x.rat.Num(), exponent); err != nil {
+			return nil, err
+		}
+		if err := checkPower(x.rat.Denom(), exponent); err != nil {
+			return nil, err
+		}
+		if n > 0 && int64(x.scale) > maxPowerBits/n {
+			return nil, fmt.Errorf("exponent %d is too large, the result would have over %d decimal places", n, maxPowerBits)
+		}
+		power := new(big.Rat).SetFrac(
+			new(big.Int).Exp(x.rat.Num(), new(big.Int).Abs(exponent), nil),
+			new(big.Int).Exp(x.rat.Denom(), new(big.Int).Abs(exponent), nil),
+		)
+		if n < 0 {
+			return NewDecimal(power.Inv(power), x.scale), nil
+		}
+		return NewDecimal(power, x.scale*int(n)), nil
+	}
+	if y.rat.Sign() == 0 {
+		if operator == Percent {
+			return nil, fmt.Errorf("decimal modulo by zero")
+		}
+		return nil, fmt.Errorf("division by zero")
+	}
+	quotient := new(big.Rat).Quo(x.rat, y.rat)
+	if operator == Percent {
+		// x - y * trunc(x / y), like integers
+		truncated := new(big.Int).Quo(quotient.Num(), quotient.Denom())
+		product := new(big.Rat).Mul(y.rat, new(big.Rat).SetInt(truncated))
+		return NewDecimal(new(big.Rat).Sub(x.rat, product), max(x.scale, y.scale)), nil
+	}
+	return NewDecimal(quotient, max(x.scale, y.scale)), nil
+}
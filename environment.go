@@ -1,65 +1,135 @@
 package glox
 
-import "fmt"
+import (
+	"fmt"
+	"maps"
+	"sync"
+)
 
 type Env interface {
 	Define(name string, value any)
+	DefineConst(name string, value any)
 	Assign(name Token, value any) error
 	AssignAt(distance int, name Token, value any) error
 	Get(name Token) (any, error)
 	GetAt(distance int, name string) (any, error)
 }
 
+// Environment maps the variables of a scope to their values. Fibers can
+// share environments: every read and write of a variable is atomic, but a
+// read followed by a write, like x = x + 1, isn't.
 type Environment struct {
 	Values    map[string]any
+	Constants map[string]bool // the names of Values that can't be reassigned
 	Enclosing Env
+
+	mu sync.RWMutex // guards Values and Constants
 }
 
 func NewEnvironment(enclosing Env) *Environment {
 	return &Environment{
 		Values:    make(map[string]any),
+		Constants: make(map[string]bool),
 		Enclosing: enclosing,
 	}
 }
 
-func (e Environment) Define(name string, value any) {
+func (e *Environment) Define(name string, value any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.Values[name] = value
+	delete(e.Constants, name)
 }
 
-func (e Environment) Assign(name Token, value any) error {
-	if _, ok := e.Values[name.Lexeme]; ok {
-		e.Values[name.Lexeme] = value
-		return nil
+func (e *Environment) DefineConst(name string, value any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.Values[name] = value
+	e.Constants[name] = true
+}
+
+// declare defines a variable declared by a script, which can't replace a
+// constant of the same scope.
+func (e *Environment) declare(name Token, value any, constant bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.Constants[name.Lexeme] {
+		return Error(name.Line, fmt.Sprintf("can't redeclare constant '%s'", name.Lexeme))
+	}
+	e.Values[name.Lexeme] = value
+	if constant {
+		e.Constants[name.Lexeme] = true
+	}
+	return nil
+}
+
+func (e *Environment) Assign(name Token, value any) error {
+	if ok, err := e.assign(name, value); ok || err != nil {
+		return err
 	}
 	if e.Enclosing != nil {
 		return e.Enclosing.Assign(name, value)
 	}
-	return Error(name.Line, fmt.Sprintf("undefined variable '%s", name.Lexeme))
+	return Error(name.Line, fmt.Sprintf("undefined variable '%s'", name.Lexeme))
+}
+
+// assign assigns the variable if it's defined in this environment.
+func (e *Environment) assign(name Token, value any) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.Constants[name.Lexeme] {
+		return true, Error(name.Line, fmt.Sprintf("can't assign to constant '%s'", name.Lexeme))
+	}
+	if _, ok := e.Values[name.Lexeme]; ok {
+		e.Values[name.Lexeme] = value
+		return true, nil
+	}
+	return false, nil
 }
 
-func (e Environment) AssignAt(distance int, name Token, value any) error {
+func (e *Environment) AssignAt(distance int, name Token, value any) error {
 	return e.Ancestor(distance).Assign(name, value)
 }
 
-func (e Environment) Get(name Token) (any, error) {
-	if value, ok := e.Values[name.Lexeme]; ok {
+func (e *Environment) Get(name Token) (any, error) {
+	e.mu.RLock()
+	value, ok := e.Values[name.Lexeme]
+	e.mu.RUnlock()
+	if ok {
 		return value, nil
 	}
 	if e.Enclosing != nil {
 		return e.Enclosing.Get(name)
 	}
-	return nil, Error(name.Line, fmt.Sprintf("undefined variable '%s", name.Lexeme))
+	return nil, Error(name.Line, fmt.Sprintf("undefined variable '%s'", name.Lexeme))
 }
 
-func (e Environment) GetAt(distance int, name string) (any, error) {
+func (e *Environment) GetAt(distance int, name string) (any, error) {
 	return e.Ancestor(distance).Get(Token{Lexeme: name})
 }
 
-func (e Environment) Ancestor(distance int) Env {
+func (e *Environment) Ancestor(distance int) Env {
 	env := e
 	for range distance {
-		e, _ := env.Enclosing.(*Environment)
-		env = *e
+		env = env.Enclosing.(*Environment)
 	}
 	return env
 }
+
+// clone returns a copy of this environment, sharing its enclosing one.
+func (e *Environment) clone() *Environment {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return &Environment{
+		Values:    maps.Clone(e.Values),
+		Constants: maps.Clone(e.Constants),
+		Enclosing: e.Enclosing,
+	}
+}
+
+// Variables returns a copy of the variables of this environment.
+func (e *Environment) Variables() map[string]any {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return maps.Clone(e.Values)
+}
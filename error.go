@@ -1,11 +1,32 @@
 package glox
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+// LoxError is an error reported at a line of the script, by the scanner,
+// the parser, the resolver or the interpreter.
+type LoxError struct {
+	Line    int
+	Where   string // like " at 'x'", or empty
+	Message string
+}
+
+func (e *LoxError) Error() string {
+	return fmt.Sprintf("[line %d] Error%s: %s", e.Line, e.Where, e.Message)
+}
 
 func Report(line int, where string, message string) error {
-	return fmt.Errorf("[line %d] Error%s: %s", line, where, message)
+	return &LoxError{Line: line, Where: where, Message: message}
 }
 
 func Error(line int, message string) error {
 	return Report(line, "", message)
 }
+
+// sentence ends message with a period, like the messages of the runtime
+// errors.
+func sentence(message string) string {
+	return strings.TrimSuffix(message, ".") + "."
+}
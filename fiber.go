@@ -0,0 +1,324 @@
+package glox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// Fibers are the Lox callables started by spawn. Each one runs on its own
+// goroutine with its own call stack, one at a time on the event loop of the
+// program, and they communicate through channels.
+// A runtime error in a fiber only ends that fiber, and is printed on stderr.
+//
+// The scheduler of a program counts its fibers, the main one included, and
+// how many of them wait on a channel. When they all do, none of them can
+// ever be woken up, so they fail with a deadlock error instead of hanging.
+//
+// exit() in any fiber ends the whole program: the other fibers stop with
+// the same *Exit at their next statement, or as soon as what they wait for
+// is interrupted. The program doesn't wait for its fibers otherwise, so the
+// ones still running when the main fiber ends are abandoned.
+type scheduler struct {
+	mu        sync.Mutex
+	wake      *sync.Cond // broadcast whenever a channel changes
+	fibers    int
+	blocked   int // the fibers waiting that weren't woken up yet
+	deadlocks int // the number of deadlocks so far
+
+	exit   atomic.Pointer[Exit] // set by the first exit()
+	exited chan struct{}        // closed once exit is set
+
+	loop eventLoop // runs one fiber at a time
+}
+
+func newScheduler() *scheduler {
+	s := &scheduler{fibers: 1, exited: make(chan struct{})}
+	s.wake = sync.NewCond(&s.mu)
+	return s
+}
+
+// stop ends the program with the status of exit, unless it's already
+// ending.
+func (s *scheduler) stop(exit *Exit) {
+	if !s.exit.CompareAndSwap(nil, exit) {
+		return
+	}
+	close(s.exited)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.broadcast()
+}
+
+// exiting returns the *Exit ending the program, if any.
+func (s *scheduler) exiting() error {
+	if exit := s.exit.Load(); exit != nil {
+		return exit
+	}
+	return nil
+}
+
+var errDeadlock = errors.New("deadlock, every fiber is blocked on a channel")
+
+// wait blocks the calling fiber until a channel changes, and lets the other
+// fibers run meanwhile. The caller holds s.mu and checks again whatever it's
+// waiting for.
+func (s *scheduler) wait(t *turn) error {
+	if err := s.exiting(); err != nil {
+		return err
+	}
+	deadlocks := s.deadlocks
+	if s.blocked+1 == s.fibers {
+		s.deadlock()
+		return errDeadlock
+	}
+	s.blocked++
+	paused := t.pause()
+	s.wake.Wait()
+	if paused {
+		s.mu.Unlock() // the fiber holding the turn may need it
+		t.resume()
+		s.mu.Lock()
+	}
+	if err := s.exiting(); err != nil {
+		return err
+	}
+	if s.deadlocks != deadlocks {
+		return errDeadlock
+	}
+	return nil
+}
+
+// broadcast wakes up the waiting fibers, so they check again what they're
+// waiting for. The caller holds s.mu.
+func (s *scheduler) broadcast() {
+	s.blocked = 0
+	s.wake.Broadcast()
+}
+
+// deadlock fails the waiting fibers. The caller holds s.mu.
+func (s *scheduler) deadlock() {
+	s.deadlocks++
+	s.broadcast()
+}
+
+// spawn calls callable on a new fiber. paren is the closing parenthesis of
+// the call, for the errors of natives.
+func (s *scheduler) spawn(interpreter *Interpreter, callable Callable, arguments []any, paren Token) {
+	s.mu.Lock()
+	s.fibers++
+	s.mu.Unlock()
+
+	fiber := *interpreter
+	fiber.Profiler = nil // the profiler only samples the main fiber
+	fiber.generator = nil
+	fiber.turn = &turn{loop: &s.loop}
+	go func() {
+		defer s.done()
+		leave := fiber.turn.enter()
+		defer leave()
+		_, err := fiber.invoke(callable, arguments, paren)
+		if err != nil && !errors.As(err, new(*Exit)) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}()
+}
+
+// done counts a fiber out once it ended.
+func (s *scheduler) done() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fibers--
+	if s.blocked > 0 && s.blocked == s.fibers {
+		s.deadlock()
+	}
+}
+
+// Channel passes values between fibers, in the order they're sent. Sending
+// to a channel with no capacity waits until the value is received, and
+// sending to a full one waits until there's room.
+type Channel struct {
+	scheduler *scheduler
+	capacity  int
+	queue     []any
+	sent      int // the number of values sent so far
+	received  int // the number of values received so far
+	closed    bool
+}
+
+func (c *Channel) String() string {
+	return "<channel>"
+}
+
+func (c *Channel) send(t *turn, value any) error {
+	s := c.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c.capacity > 0 && len(c.queue) >= c.capacity && !c.closed {
+		if err := s.wait(t); err != nil {
+			return err
+		}
+	}
+	if c.closed {
+		return errors.New("send on a closed channel")
+	}
+	c.queue = append(c.queue, value)
+	c.sent++
+	s.broadcast()
+	for ticket := c.sent; c.capacity == 0 && c.received < ticket; {
+		if err := s.wait(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receive returns the next value of the channel. ok is false once the
+// channel is closed and empty.
+func (c *Channel) receive(t *turn) (_ any, ok bool, _ error) {
+	s := c.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(c.queue) == 0 && !c.closed {
+		if err := s.wait(t); err != nil {
+			return nil, false, err
+		}
+	}
+	if len(c.queue) == 0 {
+		return nil, false, nil
+	}
+	return c.take(), true, nil
+}
+
+// take removes the next value of the channel. The caller holds the lock of
+// the scheduler.
+func (c *Channel) take() any {
+	if len(c.queue) == 0 {
+		return nil
+	}
+	value := c.queue[0]
+	c.queue = c.queue[1:]
+	c.received++
+	c.scheduler.broadcast()
+	return value
+}
+
+func (c *Channel) close() error {
+	s := c.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.closed {
+		return errors.New("close of a closed channel")
+	}
+	c.closed = true
+	s.broadcast()
+	return nil
+}
+
+// selectChannel receives from the first of the channels with a value, or
+// closed, waiting until there's one.
+func selectChannel(t *turn, channels []*Channel) (*Channel, any, error) {
+	s := channels[0].scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		for _, c := range channels {
+			if len(c.queue) > 0 || c.closed {
+				return c, c.take(), nil
+			}
+		}
+		if err := s.wait(t); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// The channel library. Like the string library, its natives take the
+// channel as their first argument, so they double as channel methods.
+var channelLibrary = map[string]Callable{
+	"send": NewNativeFunction("send", 2,
+		func(i *Interpreter, args []any) (any, error) {
+			c, err := channelArg("send", args[0])
+			if err != nil {
+				return nil, err
+			}
+			return nil, c.send(i.turn, args[1])
+		},
+	),
+	"receive": NewNativeFunction("receive", 1,
+		func(i *Interpreter, args []any) (any, error) {
+			c, err := channelArg("receive", args[0])
+			if err != nil {
+				return nil, err
+			}
+			value, _, err := c.receive(i.turn) // nil once the channel is closed
+			return value, err
+		},
+	),
+	"close": NewNativeFunction("close", 1,
+		func(_ *Interpreter, args []any) (any, error) {
+			c, err := channelArg("close", args[0])
+			if err != nil {
+				return nil, err
+			}
+			return nil, c.close()
+		},
+	),
+}
+
+// DefineChannels installs the channel natives in env.
+func DefineChannels(env Env) {
+	for name, native := range channelLibrary {
+		env.Define(name, native)
+	}
+	env.Define("channel", NewVariadicNativeFunction("channel", 0, 1,
+		func(i *Interpreter, args []any) (any, error) {
+			capacity := int64(0)
+			if len(args) == 1 {
+				var err error
+				if capacity, err = integerArg("channel", args[0]); err != nil {
+					return nil, err
+				}
+				if capacity < 0 {
+					return nil, fmt.Errorf("channel expects a non-negative capacity, got %d", capacity)
+				}
+			}
+			return &Channel{scheduler: i.fibers, capacity: int(capacity)}, nil
+		},
+	))
+	env.Define("select", NewNativeFunction("select", 1,
+		func(i *Interpreter, args []any) (any, error) {
+			list, ok := args[0].(*List)
+			if !ok || list.Len() == 0 {
+				return nil, fmt.Errorf("select expects a list of channels")
+			}
+			elements := list.Values()
+			channels := make([]*Channel, len(elements))
+			for n, element := range elements {
+				c, err := channelArg("select", element)
+				if err != nil {
+					return nil, err
+				}
+				if n > 0 && c.scheduler != channels[0].scheduler {
+					return nil, fmt.Errorf("select expects channels of the same program")
+				}
+				channels[n] = c
+			}
+			c, value, err := selectChannel(i.turn, channels)
+			if err != nil {
+				return nil, err
+			}
+			return NewList([]any{c, value}), nil
+		},
+	))
+}
+
+func channelArg(native string, arg any) (*Channel, error) {
+	c, ok := arg.(*Channel)
+	if !ok {
+		return nil, fmt.Errorf("%s expects a channel, got %T", native, arg)
+	}
+	return c, nil
+}
@@ -0,0 +1,76 @@
+package glox
+
+import "sync"
+
+// Future is a value that isn't known yet, like the response of a slow
+// service. Natives return it right away and the host completes it later,
+// from any goroutine. await parks the fiber awaiting it until then, and
+// the event loop runs the other fibers meanwhile. The fiber doesn't count
+// as blocked for the deadlock detection, since the host can still complete
+// the future.
+type Future struct {
+	once  sync.Once
+	done  chan struct{}
+	value any
+	err   error
+}
+
+func NewFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+func (f *Future) String() string {
+	return "<future>"
+}
+
+// Complete completes the future with value. Only the first completion of a
+// future counts.
+func (f *Future) Complete(value any) {
+	f.settle(value, nil)
+}
+
+// Fail completes the future with err, which await reports as a runtime
+// error.
+func (f *Future) Fail(err error) {
+	f.settle(nil, err)
+}
+
+func (f *Future) settle(value any, err error) {
+	f.once.Do(func() {
+		f.value, f.err = value, err
+		close(f.done)
+	})
+}
+
+// Done returns a channel closed once the future is completed.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Await waits until the future is completed and returns its outcome.
+func (f *Future) Await() (any, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+// AsyncNativeFunctionHandler runs on a goroutine of its own, while the
+// script goes on, so it gets no interpreter.
+type AsyncNativeFunctionHandler func([]any) (any, error)
+
+// NewAsyncNativeFunction returns a native that runs handler on a goroutine
+// and returns a future completed with its outcome. The script may change
+// the lists among the arguments meanwhile, so handler reads them with the
+// methods of List rather than through Elements.
+func NewAsyncNativeFunction(name string, arity int, handler AsyncNativeFunctionHandler) Callable {
+	return NewNativeFunction(name, arity, func(_ *Interpreter, args []any) (any, error) {
+		future := NewFuture()
+		go func() {
+			if value, err := handler(args); err != nil {
+				future.Fail(err)
+			} else {
+				future.Complete(value)
+			}
+		}()
+		return future, nil
+	})
+}
@@ -0,0 +1,119 @@
+package glox_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tangzero/glox"
+)
+
+func TestAsyncNatives(t *testing.T) {
+	script, err := glox.Compile(`
+var a = double(1);
+var b = double(2);
+print await a + await b;
+await double(nil);
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	globals := glox.DefaultGlobals()
+	globals.Define("double", glox.NewAsyncNativeFunction("double", 1,
+		func(args []any) (any, error) {
+			n, ok := args[0].(int64)
+			if !ok {
+				return nil, errors.New("double expects an integer")
+			}
+			return 2 * n, nil
+		},
+	))
+	var output strings.Builder
+	interpreter := script.NewInterpreter(globals)
+	interpreter.Stdout = &output
+	err = interpreter.Interpret(script.Program())
+
+	if output.String() != "6\n" {
+		t.Errorf("expected output %q, got %q", "6\n", output.String())
+	}
+	expected := "[line 5] Error: double expects an integer."
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestHostCompletesFuture(t *testing.T) {
+	script, err := glox.Compile("print await pending;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	future := glox.NewFuture()
+	globals := glox.DefaultGlobals()
+	globals.Define("pending", future)
+
+	var output strings.Builder
+	interpreter := script.NewInterpreter(globals)
+	interpreter.Stdout = &output
+	done := make(chan error)
+	go func() { done <- interpreter.Interpret(script.Program()) }()
+
+	future.Complete("ready")
+	future.Fail(errors.New("too late")) // only the first completion counts
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "ready\n" {
+		t.Errorf("expected output %q, got %q", "ready\n", output.String())
+	}
+}
+
+func TestEventLoopRunsFibersWhileAwaiting(t *testing.T) {
+	script, err := glox.Compile(`
+fun work() {
+  var n = 0;
+  while (n < 5000) n += 1;
+  complete(n);
+}
+spawn work();
+print await pending;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	future := glox.NewFuture()
+	globals := glox.DefaultGlobals()
+	globals.Define("pending", future)
+	globals.Define("complete", glox.NewNativeFunction("complete", 1,
+		func(_ *glox.Interpreter, args []any) (any, error) {
+			future.Complete(args[0])
+			return nil, nil
+		},
+	))
+
+	var output strings.Builder
+	interpreter := script.NewInterpreter(globals)
+	interpreter.Stdout = &output
+	if err := interpreter.Interpret(script.Program()); err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "5000\n" {
+		t.Errorf("expected output %q, got %q", "5000\n", output.String())
+	}
+}
+
+func TestHostFailsFuture(t *testing.T) {
+	script, err := glox.Compile("await pending;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	future := glox.NewFuture()
+	future.Fail(errors.New("service unavailable."))
+	globals := glox.DefaultGlobals()
+	globals.Define("pending", future)
+
+	err = script.Run(globals)
+	expected := "[line 1] Error: service unavailable."
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
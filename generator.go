@@ -0,0 +1,121 @@
+package glox
+
+import (
+	"errors"
+	"sync"
+)
+
+// Generator is the value returned by a call to a function with a yield. Its
+// body runs on a goroutine of its own, in lockstep with the consumer: the
+// consumer asks for a value and waits, and the body runs until its next
+// yield, or until it finishes, and waits for the next request. Fibers can
+// share a generator, but only one at a time can resume it.
+type Generator struct {
+	name   string
+	run    func(interpreter *Interpreter) error
+	resume chan bool // true to run to the next yield, false to stop
+	yields chan yielded
+	body   *Interpreter // runs the body, set by the first Next
+
+	mu   sync.Mutex // guards done and running
+	done bool
+	// the body is running, so it can't be resumed, like when it iterates
+	// its own generator or another fiber resumed it
+	running bool
+}
+
+// yielded is a value the body of a generator yielded, or the outcome of
+// the body if it finished.
+type yielded struct {
+	value any
+	err   error
+	done  bool
+}
+
+var (
+	// errGeneratorClosed unwinds the body of a generator that was closed.
+	errGeneratorClosed = errors.New("generator closed")
+
+	ErrGeneratorRunning = errors.New("generator is already running")
+)
+
+func NewGenerator(name string, run func(interpreter *Interpreter) error) *Generator {
+	return &Generator{name: name, run: run}
+}
+
+func (g *Generator) String() string {
+	return "<generator " + g.name + ">"
+}
+
+// Next resumes the body of the generator until it yields a value or
+// finishes. The error of a body that fails is returned once, and done is
+// true once the body finished.
+func (g *Generator) Next(interpreter *Interpreter) (value any, done bool, err error) {
+	g.mu.Lock()
+	if g.done {
+		g.mu.Unlock()
+		return nil, true, nil
+	}
+	if g.running {
+		g.mu.Unlock()
+		return nil, false, ErrGeneratorRunning
+	}
+	g.running = true
+	g.mu.Unlock()
+
+	// the channels are only used by the caller that set running
+	if g.yields == nil {
+		g.start(interpreter)
+	} else {
+		g.body.turn = interpreter.turn // the body runs on the turn of its consumer
+		g.resume <- true
+	}
+	result := <-g.yields
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.running = false
+	g.done = result.done
+	return result.value, result.done, result.err
+}
+
+// Close stops the body of a suspended generator, so its goroutine ends.
+func (g *Generator) Close() {
+	g.mu.Lock()
+	if g.done || g.running {
+		g.mu.Unlock()
+		return
+	}
+	g.done = true
+	g.mu.Unlock()
+	if g.yields != nil {
+		g.resume <- false
+		<-g.yields
+	}
+}
+
+func (g *Generator) start(interpreter *Interpreter) {
+	g.resume = make(chan bool)
+	g.yields = make(chan yielded)
+	body := *interpreter
+	body.generator = g
+	body.Profiler = nil // like fibers, so the body doesn't race the sampling of the consumer
+	g.body = &body
+	go func() {
+		err := g.run(&body)
+		if _, ok := err.(*ReturnValue); ok || err == errGeneratorClosed {
+			err = nil
+		}
+		g.yields <- yielded{err: err, done: true}
+	}()
+}
+
+// yield hands value to the consumer and waits until it asks for the next
+// one.
+func (g *Generator) yield(value any) error {
+	g.yields <- yielded{value: value}
+	if !<-g.resume {
+		return errGeneratorClosed
+	}
+	return nil
+}
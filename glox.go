@@ -5,63 +5,84 @@ package glox
 import (
 	"bufio"
 	"fmt"
+	"math"
+	"math/big"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
-func RunFile(path string) error {
+// RunFile runs the script at path. The extra args are available to the
+// script through the args global.
+func RunFile(path string, args ...string) error {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("could not read file: %v", err)
 	}
-	program, err := Parse(string(bytes))
+	return Run(string(bytes), args...)
+}
+
+func Run(source string, args ...string) error {
+	script, err := Compile(source)
 	if err != nil {
 		return err
 	}
+	script.PrintWarnings()
+	return script.Run(ScriptGlobals(args...))
+}
 
-	interpreter := NewInterpreter(DefaultGlobals())
-	resolver := NewResolver(interpreter)
-
-	if err := resolver.Resolve(program); err != nil {
-		return fmt.Errorf("resolution error: %v", err)
+// CheckFile reports the syntax and resolution errors of a script without
+// running it.
+func CheckFile(path string) error {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read file: %v", err)
 	}
-	return interpreter.Interpret(program)
+	script, err := Compile(string(bytes))
+	if err != nil {
+		return err
+	}
+	script.PrintWarnings()
+	return nil
 }
 
-func RunPrompt() error {
-	interpreter := NewInterpreter(DefaultGlobals())
-	resolver := NewResolver(interpreter)
-
-	fmt.Println("Glox REPL. Press Ctrl+C to exit.")
-	prompt := "> "
-	scanner := bufio.NewScanner(os.Stdin)
-
-	for fmt.Print(prompt); scanner.Scan(); fmt.Print(prompt) {
-		program, err := Parse(scanner.Text())
-		if err != nil {
-			fmt.Println(err)
-			continue
-		}
-
-		if err := resolver.Resolve(program); err != nil {
-			fmt.Println(err)
-			continue
-		}
+// CoverFile runs a script like RunFile while recording its coverage. The
+// coverage is returned even if the script fails at runtime.
+func CoverFile(path string, args ...string) (*Coverage, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file: %v", err)
+	}
+	script, err := Compile(string(bytes))
+	if err != nil {
+		return nil, err
+	}
+	script.PrintWarnings()
 
-		// if the input is a single expression, wrap it in a print statement.
-		if expr, ok := program[0].(*ExpressionStmt); ok && len(program) == 1 {
-			program = Program{&PrintStmt{Expr: expr.Expr}}
-		}
+	interpreter := script.NewInterpreter(ScriptGlobals(args...))
+	interpreter.Coverage = NewCoverage(path, string(bytes), script.Program())
+	return interpreter.Coverage, interpreter.Interpret(script.Program())
+}
 
-		if err := interpreter.Interpret(program); err != nil {
-			fmt.Println(err)
-		}
+// ProfileFile runs a script like RunFile while sampling its call stack
+// every rate. The profiler is returned even if the script fails at runtime.
+func ProfileFile(path string, rate time.Duration, args ...string) (*Profiler, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file: %v", err)
 	}
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading input: %v", err)
+	script, err := Compile(string(bytes))
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	script.PrintWarnings()
+
+	interpreter := script.NewInterpreter(ScriptGlobals(args...))
+	interpreter.Profiler = NewProfiler(path, rate)
+	interpreter.Profiler.Start()
+	defer interpreter.Profiler.Stop()
+	return interpreter.Profiler, interpreter.Interpret(script.Program())
 }
 
 func Parse(source string) (Program, error) {
@@ -82,6 +103,20 @@ func DefaultGlobals() Env {
 			return float64(time.Now().UnixMilli()), nil
 		},
 	))
+	env.Define("sleep", NewNativeFunction("sleep", 1,
+		func(_ *Interpreter, args []any) (any, error) {
+			ms, err := integerArg("sleep", args[0])
+			if err != nil {
+				return nil, err
+			}
+			if ms < 0 {
+				return nil, fmt.Errorf("sleep expects a non-negative duration, got %d", ms)
+			}
+			future := NewFuture()
+			time.AfterFunc(time.Duration(ms)*time.Millisecond, func() { future.Complete(nil) })
+			return future, nil
+		},
+	))
 	env.Define("prompt", NewNativeFunction("prompt", 1,
 		func(i *Interpreter, args []any) (any, error) {
 			fmt.Println(args[0])
@@ -96,16 +131,132 @@ func DefaultGlobals() Env {
 		},
 	))
 
+	DefineMath(env, uint64(time.Now().UnixNano()))
+	DefineStrings(env)
+	DefineChannels(env)
+
+	// to let scripts act as command-line tools
+	env.Define("exit", NewNativeFunction("exit", 1,
+		func(interpreter *Interpreter, args []any) (any, error) {
+			code, err := integerArg("exit", args[0])
+			if err != nil {
+				return nil, err
+			}
+			exit := &Exit{Code: int(code)}
+			interpreter.fibers.stop(exit) // for the other fibers
+			return nil, exit
+		},
+	))
+	env.Define("env", NewNativeFunction("env", 1,
+		func(_ *Interpreter, args []any) (any, error) {
+			name, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("env expects a string, got %T", args[0])
+			}
+			if value, ok := os.LookupEnv(name); ok {
+				return value, nil
+			}
+			return nil, nil
+		},
+	))
+	env.Define("freeze", NewNativeFunction("freeze", 1,
+		func(_ *Interpreter, args []any) (any, error) {
+			// the other values are immutable already
+			if list, ok := args[0].(*List); ok {
+				list.Freeze()
+			}
+			return args[0], nil
+		},
+	))
+
 	// to convert between types
 	env.Define("number", NewNativeFunction("number", 1,
 		func(_ *Interpreter, args []any) (any, error) {
-			return strconv.ParseFloat(args[0].(string), 64)
+			text, err := stringArg("number", args[0])
+			if err != nil {
+				return nil, err
+			}
+			return parseNumber(strings.TrimSpace(text))
+		},
+	))
+	env.Define("int", NewNativeFunction("int", 1,
+		func(_ *Interpreter, args []any) (any, error) {
+			var integer int64
+			var ok bool
+			switch x := args[0].(type) {
+			case float64:
+				integer, ok = toInteger(math.Trunc(x))
+			case Decimal:
+				integer, ok = toInteger(new(big.Int).Quo(x.rat.Num(), x.rat.Denom()))
+			default:
+				if !isNumber(x) {
+					return nil, fmt.Errorf("int expects a number, got %T", x)
+				}
+				integer, ok = toInteger(x)
+			}
+			if !ok {
+				return nil, fmt.Errorf("int can't convert %s to an integer", stringify(args[0]))
+			}
+			return integer, nil
+		},
+	))
+	env.Define("float", NewNativeFunction("float", 1,
+		func(_ *Interpreter, args []any) (any, error) {
+			return numberArg("float", args[0])
+		},
+	))
+	env.Define("bigint", NewNativeFunction("bigint", 1,
+		func(_ *Interpreter, args []any) (any, error) {
+			switch x := args[0].(type) {
+			case string:
+				if integer, ok := new(big.Int).SetString(strings.TrimSpace(x), 10); ok {
+					return integer, nil
+				}
+				return nil, fmt.Errorf("bigint can't parse %q", x)
+			case float64:
+				if x != math.Trunc(x) || math.IsInf(x, 0) {
+					return nil, fmt.Errorf("bigint can't convert %s to an integer", stringify(x))
+				}
+				integer, _ := big.NewFloat(x).Int(nil)
+				return integer, nil
+			case Decimal:
+				return new(big.Int).Quo(x.rat.Num(), x.rat.Denom()), nil
+			case int64, *big.Int:
+				return toBig(x), nil
+			}
+			return nil, fmt.Errorf("bigint expects a number or a string, got %T", args[0])
+		},
+	))
+	env.Define("decimal", NewNativeFunction("decimal", 1,
+		func(_ *Interpreter, args []any) (any, error) {
+			switch x := args[0].(type) {
+			case string:
+				return ParseDecimal(strings.TrimSpace(x))
+			case float64:
+				// the shortest text that reads back as the float, so
+				// decimal(0.1) is 0.1 and not 0.1000000000000000055...
+				return ParseDecimal(strconv.FormatFloat(x, 'f', -1, 64))
+			case int64, *big.Int, Decimal:
+				return toDecimal(x), nil
+			}
+			return nil, fmt.Errorf("decimal expects a number or a string, got %T", args[0])
 		},
 	))
 	env.Define("string", NewNativeFunction("string", 1,
 		func(_ *Interpreter, args []any) (any, error) {
-			return fmt.Sprintf("%v", args[0]), nil
+			return stringify(args[0]), nil
 		},
 	))
 	return env
 }
+
+// ScriptGlobals returns the default globals plus the args list of a script.
+func ScriptGlobals(args ...string) Env {
+	env := DefaultGlobals()
+	elements := make([]any, len(args))
+	for i, arg := range args {
+		elements[i] = arg
+	}
+	env.Define("args", NewList(elements))
+	return env
+}
@@ -2,6 +2,13 @@ module github.com/tangzero/glox
 
 go 1.25.1
 
-require github.com/samber/lo v1.51.0
+require (
+	github.com/peterh/liner v1.2.2
+	github.com/samber/lo v1.51.0
+)
 
-require golang.org/x/text v0.22.0 // indirect
+require (
+	github.com/mattn/go-runewidth v0.0.3 // indirect
+	golang.org/x/sys v0.32.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
+)
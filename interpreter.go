@@ -3,6 +3,15 @@ package glox
 import (
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/samber/lo"
 )
 
 var _ Visitor = (*Interpreter)(nil)
@@ -10,14 +19,29 @@ var _ Visitor = (*Interpreter)(nil)
 type Interpreter struct {
 	Env     Env
 	Globals Env
-	Locals  map[Expr]int
+	Stdout  io.Writer // where print statements write to
+
+	Coverage *Coverage // optional, records executed statements and branches
+	Profiler *Profiler // optional, samples the Lox call stack
+
+	locals   map[Expr]int // the depth of the local variables, from Resolve
+	compiled bool         // locals belong to a Script, so Resolve can't add to them
+	// guards locals when code is resolved while fibers run, like in the REPL
+	localsMu  *sync.RWMutex
+	generator *Generator // the generator whose body this interpreter runs
+	fibers    *scheduler // shared by the fibers of the program
+	turn      *turn      // the share of this fiber in the event loop
 }
 
 func NewInterpreter(globals Env) *Interpreter {
+	fibers := newScheduler()
 	return &Interpreter{
 		Env:     globals,
 		Globals: globals,
-		Locals:  make(map[Expr]int),
+		locals:  make(map[Expr]int),
+		Stdout:  os.Stdout,
+		fibers:  fibers,
+		turn:    &turn{loop: &fibers.loop},
 	}
 }
 
@@ -30,63 +54,71 @@ func (i *Interpreter) VisitBinaryExpr(expr *BinaryExpr) (any, error) {
 	if err != nil {
 		return nil, err
 	}
+	return i.binary(expr.Operator, left, right)
+}
 
-	switch expr.Operator.Type {
-	case Greater:
-		if err := checkNumberOperands(expr.Operator, left, right); err != nil {
+func (i *Interpreter) binary(operator Token, left, right any) (any, error) {
+	switch operator.Type {
+	case Greater, GreaterEqual, Less, LessEqual:
+		if err := checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
-		return left.(float64) > right.(float64), nil
-	case GreaterEqual:
-		if err := checkNumberOperands(expr.Operator, left, right); err != nil {
-			return nil, err
+		c, ok := compareNumbers(left, right)
+		if !ok {
+			return false, nil // NaN
 		}
-		return left.(float64) >= right.(float64), nil
-	case Less:
-		if err := checkNumberOperands(expr.Operator, left, right); err != nil {
-			return nil, err
+		switch operator.Type {
+		case Greater:
+			return c > 0, nil
+		case GreaterEqual:
+			return c >= 0, nil
+		case Less:
+			return c < 0, nil
+		default:
+			return c <= 0, nil
 		}
-		return left.(float64) < right.(float64), nil
-	case LessEqual:
-		if err := checkNumberOperands(expr.Operator, left, right); err != nil {
-			return nil, err
-		}
-		return left.(float64) <= right.(float64), nil
 	case EqualEqual:
 		return isEqual(left, right), nil
 	case BangEqual:
 		return !isEqual(left, right), nil
-	case Minus:
-		if err := checkNumberOperands(expr.Operator, left, right); err != nil {
+	case Minus, Slash, Star, Percent, StarStar:
+		if err := checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
-		return left.(float64) - right.(float64), nil
-	case Slash:
-		if err := checkNumberOperands(expr.Operator, left, right); err != nil {
+		return i.arithmetic(operator, left, right)
+	case Ampersand, Pipe, Caret, LessLess, GreaterGreater:
+		if err := checkIntegerOperands(operator, left, right); err != nil {
 			return nil, err
 		}
-		return left.(float64) / right.(float64), nil
-	case Star:
-		if err := checkNumberOperands(expr.Operator, left, right); err != nil {
-			return nil, err
+		value, err := bitwise(operator.Type, left, right)
+		if err != nil {
+			return nil, Error(operator.Line, err.Error()+".")
 		}
-		return left.(float64) * right.(float64), nil
+		return value, nil
 	case Plus:
-		if isFloat(left) && isFloat(right) {
-			return left.(float64) + right.(float64), nil
+		if isNumber(left) && isNumber(right) {
+			return i.arithmetic(operator, left, right)
 		}
 		if isString(left) && isString(right) {
 			return left.(string) + right.(string), nil
 		}
 		if isString(left) || isString(right) {
-			return fmt.Sprintf("%v%v", left, right), nil
+			return stringify(left) + stringify(right), nil
 		}
-		return nil, Error(expr.Operator.Line, fmt.Sprintf("'+' operation not supported for %T and %T.", left, right))
+		return nil, Error(operator.Line, fmt.Sprintf("'+' operation not supported for %T and %T.", left, right))
 	default:
-		return nil, Error(expr.Operator.Line, "unknown binary operator.")
+		return nil, Error(operator.Line, "unknown binary operator.")
 	}
 }
 
+func (i *Interpreter) arithmetic(operator Token, left, right any) (any, error) {
+	value, err := arithmetic(operator.Type, left, right)
+	if err != nil {
+		return nil, Error(operator.Line, err.Error()+".")
+	}
+	return value, nil
+}
+
 func (i *Interpreter) VisitGroupingExpr(expr *GroupingExpr) (any, error) {
 	return i.Evaluate(expr.Expression)
 }
@@ -106,7 +138,12 @@ func (i *Interpreter) VisitUnaryExpr(expr *UnaryExpr) (any, error) {
 		if err := checkNumberOperand(expr.Operator, right); err != nil {
 			return nil, err
 		}
-		return -(right.(float64)), nil
+		return negate(right), nil
+	case Tilde:
+		if !isInteger(right) {
+			return nil, Error(expr.Operator.Line, fmt.Sprintf("operand must be an integer, got %T.", right))
+		}
+		return complement(right), nil
 	case Bang:
 		return !isTruthy(right), nil
 	default:
@@ -123,11 +160,54 @@ func (i *Interpreter) VisitAssignExpr(expr *AssignExpr) (any, error) {
 	if err != nil {
 		return nil, err
 	}
-	depth, ok := i.Locals[expr]
-	if ok {
-		return value, i.Env.AssignAt(depth, expr.Name, value)
+	return value, i.AssignVariable(expr.Name, expr, value)
+}
+
+// the binary operators applied by the compound assignments
+var compoundOperators = map[TokenType]TokenType{
+	PlusEqual:    Plus,
+	MinusEqual:   Minus,
+	StarEqual:    Star,
+	SlashEqual:   Slash,
+	PercentEqual: Percent,
+}
+
+func (i *Interpreter) VisitCompoundAssignExpr(expr *CompoundAssignExpr) (any, error) {
+	current, err := i.LookupVariable(expr.Name, expr)
+	if err != nil {
+		return nil, err
+	}
+	operand, err := i.Evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	operator := expr.Operator
+	operator.Type = compoundOperators[operator.Type]
+	value, err := i.binary(operator, current, operand)
+	if err != nil {
+		return nil, err
 	}
-	return value, i.Globals.Assign(expr.Name, value)
+	return value, i.AssignVariable(expr.Name, expr, value)
+}
+
+func (i *Interpreter) VisitIncrementExpr(expr *IncrementExpr) (any, error) {
+	current, err := i.LookupVariable(expr.Name, expr)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNumberOperand(expr.Operator, current); err != nil {
+		return nil, err
+	}
+	operator := expr.Operator
+	operator.Type = lo.Ternary(operator.Type == PlusPlus, Plus, Minus)
+	value, err := i.arithmetic(operator, current, int64(1))
+	if err != nil {
+		return nil, err
+	}
+	if err := i.AssignVariable(expr.Name, expr, value); err != nil {
+		return nil, err
+	}
+	return lo.Ternary(expr.Prefix, value, current), nil
 }
 
 func (i *Interpreter) VisitLogicalExpr(expr *LogicalExpr) (any, error) {
@@ -136,46 +216,383 @@ func (i *Interpreter) VisitLogicalExpr(expr *LogicalExpr) (any, error) {
 		return nil, err
 	}
 
-	if expr.Operator.Type == Or {
-		if isTruthy(left) {
-			return left, nil
-		}
-	} else {
-		if !isTruthy(left) {
-			return left, nil
-		}
+	var shortCircuit bool
+	switch expr.Operator.Type {
+	case Or:
+		shortCircuit = isTruthy(left)
+	case And:
+		shortCircuit = !isTruthy(left)
+	case QuestionQuestion:
+		shortCircuit = left != nil
+	}
+	i.Coverage.Branch(expr, !shortCircuit)
+	if shortCircuit {
+		return left, nil
 	}
 
 	return i.Evaluate(expr.Right)
 }
 
-func (i *Interpreter) VisitCallExpr(expr *CallExpr) (any, error) {
-	callee, err := i.Evaluate(expr.Callee)
+func (i *Interpreter) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	condition, err := i.Evaluate(expr.Condition)
 	if err != nil {
 		return nil, err
 	}
+	i.Coverage.Branch(expr, isTruthy(condition))
+	if isTruthy(condition) {
+		return i.Evaluate(expr.Then)
+	}
+	return i.Evaluate(expr.Else)
+}
+
+func (i *Interpreter) VisitCallExpr(expr *CallExpr) (any, error) {
+	value, _, err := i.call(expr)
+	return value, err
+}
+
+func (i *Interpreter) call(expr *CallExpr) (_ any, skipped bool, _ error) {
+	callable, arguments, skipped, err := i.callee(expr)
+	if err != nil || skipped {
+		return nil, skipped, err
+	}
+	value, err := i.invoke(callable, arguments, expr.Paren)
+	return value, false, err
+}
+
+// invoke calls callable, turning the errors of natives into runtime errors
+// at the line of paren.
+func (i *Interpreter) invoke(callable Callable, arguments []any, paren Token) (any, error) {
+	i.Profiler.Enter(callable)
+	defer i.Profiler.Exit()
+	value, err := callable.Call(i, arguments)
+	if _, ok := nativeName(callable); ok && err != nil {
+		return nil, nativeError(err, paren.Line)
+	}
+	return value, err
+}
+
+// nativeError reports the failure of a native at line. The errors of the
+// Lox code a native called back, and exit(), go through as they are.
+func nativeError(err error, line int) error {
+	if errors.As(err, new(*LoxError)) || errors.As(err, new(*Exit)) {
+		return err
+	}
+	return Error(line, sentence(err.Error()))
+}
+
+// callee evaluates the callee and the arguments of a call, checking they
+// fit together.
+func (i *Interpreter) callee(expr *CallExpr) (_ Callable, _ []any, skipped bool, _ error) {
+	callee, skipped, err := i.evaluateLink(expr.Callee)
+	if err != nil || skipped {
+		return nil, nil, skipped, err
+	}
 	var arguments []any
 	for _, arg := range expr.Arguments {
 		value, err := i.Evaluate(arg)
 		if err != nil {
-			return nil, err
+			return nil, nil, false, err
+		}
+		if spread, ok := arg.(*SpreadExpr); ok {
+			list, ok := value.(*List)
+			if !ok {
+				return nil, nil, false, Error(spread.Dots.Line, fmt.Sprintf("only lists can be spread, got %T.", value))
+			}
+			arguments = append(arguments, list.Values()...)
+			continue
 		}
 		arguments = append(arguments, value)
 	}
 	callable, ok := callee.(Callable)
 	if !ok {
-		return nil, Error(expr.Paren.Line, fmt.Sprintf("value of type '%T' is not callable.", callee))
+		return nil, nil, false, Error(expr.Paren.Line, fmt.Sprintf("value of type '%T' is not callable.", callee))
+	}
+	if len(expr.Named) > 0 {
+		if arguments, err = i.namedArguments(expr, callable, arguments); err != nil {
+			return nil, nil, false, err
+		}
+	}
+	if arity := callable.Arity(); !arity.Accepts(len(arguments)) {
+		if name, ok := nativeName(callable); ok {
+			return nil, nil, false, Error(expr.Paren.Line, fmt.Sprintf("%s expects %s arguments but got %d.", name, arity, len(arguments)))
+		}
+		return nil, nil, false, Error(expr.Paren.Line, fmt.Sprintf("expected %s arguments but got %d.", arity, len(arguments)))
+	}
+	return callable, arguments, false, nil
+}
+
+// nativeName returns the name of a native or of the native bound as a
+// method.
+func nativeName(callable Callable) (string, bool) {
+	switch callable := callable.(type) {
+	case *NativeFunction:
+		return callable.name, true
+	case *BoundMethod:
+		return nativeName(callable.method)
+	}
+	return "", false
+}
+
+// namedArguments places the named arguments of a call after its other
+// arguments, at the positions of their parameters.
+func (i *Interpreter) namedArguments(expr *CallExpr, callable Callable, arguments []any) ([]any, error) {
+	var params ParamList
+	switch callable := callable.(type) {
+	case *Function:
+		params = callable.stmt.ParamList
+	case *Lambda:
+		params = callable.expr.ParamList
+	default:
+		return nil, Error(expr.Paren.Line, fmt.Sprintf("%s doesn't take named arguments.", callable))
+	}
+	for _, arg := range expr.Named {
+		value, err := i.Evaluate(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		n := slices.IndexFunc(params.Params, func(param Token) bool { return param.Lexeme == arg.Name.Lexeme })
+		switch {
+		case n < 0:
+			return nil, Error(arg.Name.Line, fmt.Sprintf("%s has no parameter '%s'.", callable, arg.Name.Lexeme))
+		case n < len(arguments) && arguments[n] != (noArgument{}):
+			return nil, Error(arg.Name.Line, fmt.Sprintf("argument '%s' given twice.", arg.Name.Lexeme))
+		}
+		for len(arguments) <= n {
+			arguments = append(arguments, noArgument{})
+		}
+		arguments[n] = value
+	}
+	for n, param := range params.Params[:params.Arity().Min] {
+		if arguments[n] == (noArgument{}) {
+			return nil, Error(expr.Paren.Line, fmt.Sprintf("missing argument '%s'.", param.Lexeme))
+		}
+	}
+	return arguments, nil
+}
+
+// evaluateLink evaluates the callee of a call or the object of an index or
+// a property access. skipped is true if it's a chain of those where a ?.
+// found a nil, like a?.b.c() with a nil a, so the rest of the chain is
+// skipped and evaluates to nil too.
+func (i *Interpreter) evaluateLink(expr Expr) (_ any, skipped bool, _ error) {
+	switch expr := expr.(type) {
+	case *CallExpr:
+		return i.call(expr)
+	case *IndexExpr:
+		return i.index(expr)
+	case *GetExpr:
+		return i.get(expr)
+	}
+	value, err := i.Evaluate(expr)
+	return value, false, err
+}
+
+func (i *Interpreter) VisitSpreadExpr(expr *SpreadExpr) (any, error) {
+	return i.Evaluate(expr.Value) // calls spread the elements
+}
+
+// VisitAwaitExpr parks the current fiber until the future is completed,
+// and the event loop runs the other fibers meanwhile.
+func (i *Interpreter) VisitAwaitExpr(expr *AwaitExpr) (any, error) {
+	value, err := i.Evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	future, ok := value.(*Future)
+	if !ok {
+		return nil, Error(expr.Keyword.Line, fmt.Sprintf("value of type '%T' can't be awaited.", value))
+	}
+	select {
+	case <-future.Done():
+	default:
+		paused := i.turn.pause()
+		select {
+		case <-future.Done():
+		case <-i.fibers.exited:
+		}
+		if paused {
+			i.turn.resume()
+		}
+		if err := i.fibers.exiting(); err != nil {
+			return nil, err
+		}
 	}
-	if len(arguments) != callable.Arity() {
-		return nil, Error(expr.Paren.Line, fmt.Sprintf("expected %d arguments but got %d.", callable.Arity(), len(arguments)))
+	value, err = future.Await()
+	if err != nil {
+		return nil, Error(expr.Keyword.Line, sentence(err.Error()))
 	}
-	return callable.Call(i, arguments)
+	return value, nil
 }
 
 func (i *Interpreter) VisitLambdaExpr(expr *LambdaExpr) (any, error) {
 	return NewLambda(i.Env, expr), nil
 }
 
+func (i *Interpreter) VisitListExpr(expr *ListExpr) (any, error) {
+	elements := make([]any, 0, len(expr.Elements))
+	for _, element := range expr.Elements {
+		value, err := i.Evaluate(element)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, value)
+	}
+	return NewList(elements), nil
+}
+
+func (i *Interpreter) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	value, _, err := i.index(expr)
+	return value, err
+}
+
+func (i *Interpreter) index(expr *IndexExpr) (_ any, skipped bool, _ error) {
+	object, skipped, err := i.evaluateLink(expr.Object)
+	if err != nil || skipped {
+		return nil, skipped, err
+	}
+	index, err := i.Evaluate(expr.Index)
+	if err != nil {
+		return nil, false, err
+	}
+	switch object := object.(type) {
+	case *List:
+		n, err := checkIndex(expr.Bracket, index, object.Len())
+		if err != nil {
+			return nil, false, err
+		}
+		return object.Get(int(n)), false, nil
+	case string:
+		// strings are indexed by character
+		n, err := checkIndex(expr.Bracket, index, utf8.RuneCountInString(object))
+		if err != nil {
+			return nil, false, err
+		}
+		return runeAt(object, n), false, nil
+	}
+	return nil, false, Error(expr.Bracket.Line, fmt.Sprintf("value of type '%T' can't be indexed.", object))
+}
+
+// runeAt returns the nth character of s, which has more than n.
+func runeAt(s string, n int64) string {
+	for ; n > 0; n-- {
+		_, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return string(r)
+}
+
+func (i *Interpreter) VisitSetIndexExpr(expr *SetIndexExpr) (any, error) {
+	object, err := i.Evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	index, err := i.Evaluate(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+	var operand any
+	if expr.Value != nil {
+		if operand, err = i.Evaluate(expr.Value); err != nil {
+			return nil, err
+		}
+	}
+	list, ok := object.(*List)
+	if !ok {
+		return nil, Error(expr.Bracket.Line, fmt.Sprintf("value of type '%T' doesn't support index assignment.", object))
+	}
+	if list.Frozen() {
+		return nil, Error(expr.Bracket.Line, "can't modify a frozen list.")
+	}
+	n, err := checkIndex(expr.Bracket, index, list.Len())
+	if err != nil {
+		return nil, err
+	}
+	// like for variables, an update isn't atomic: another fiber can set the
+	// element between the Get and the Set
+	current := list.Get(int(n))
+	value, err := i.update(expr.Operator, current, operand)
+	if err != nil {
+		return nil, err
+	}
+	if !list.Set(int(n), value) {
+		return nil, Error(expr.Bracket.Line, "can't modify a frozen list.")
+	}
+	return lo.Ternary(expr.Value == nil && !expr.Prefix, current, value), nil
+}
+
+// update returns the new value of an element after an assignment or an
+// increment with operator.
+func (i *Interpreter) update(operator Token, current, operand any) (any, error) {
+	switch operator.Type {
+	case Equal:
+		return operand, nil
+	case PlusPlus, MinusMinus:
+		if err := checkNumberOperand(operator, current); err != nil {
+			return nil, err
+		}
+		operator.Type = lo.Ternary(operator.Type == PlusPlus, Plus, Minus)
+		return i.arithmetic(operator, current, int64(1))
+	}
+	operator.Type = compoundOperators[operator.Type]
+	return i.binary(operator, current, operand)
+}
+
+// checkIndex converts index to a position in a sequence of length elements.
+func checkIndex(bracket Token, index any, length int) (int64, error) {
+	n, ok := toInteger(index)
+	if !ok {
+		return 0, Error(bracket.Line, fmt.Sprintf("index must be an integer, got %s.", stringify(index)))
+	}
+	if n < 0 || n >= int64(length) {
+		return 0, Error(bracket.Line, fmt.Sprintf("index %d out of range [0, %d).", n, length))
+	}
+	return n, nil
+}
+
+func (i *Interpreter) VisitGetExpr(expr *GetExpr) (any, error) {
+	value, _, err := i.get(expr)
+	return value, err
+}
+
+func (i *Interpreter) get(expr *GetExpr) (_ any, skipped bool, _ error) {
+	object, skipped, err := i.evaluateLink(expr.Object)
+	if err != nil || skipped {
+		return nil, skipped, err
+	}
+	if object == nil && expr.Optional {
+		return nil, true, nil
+	}
+	name := expr.Name.Lexeme
+	switch object.(type) {
+	case string:
+		if method, ok := stringLibrary[name]; ok {
+			return NewBoundMethod(object, method), false, nil
+		}
+	case *List:
+		if slices.Contains(listMethods, name) {
+			return NewBoundMethod(object, stringLibrary[name]), false, nil
+		}
+	case *Channel:
+		if method, ok := channelLibrary[name]; ok {
+			return NewBoundMethod(object, method), false, nil
+		}
+	}
+	return nil, false, Error(expr.Name.Line, fmt.Sprintf("value of type '%T' has no property '%s'.", object, name))
+}
+
+func (i *Interpreter) VisitInterpolationExpr(expr *InterpolationExpr) (any, error) {
+	var value strings.Builder
+	for _, part := range expr.Parts {
+		v, err := i.Evaluate(part)
+		if err != nil {
+			return nil, err
+		}
+		value.WriteString(stringify(v))
+	}
+	return value.String(), nil
+}
+
 func (i *Interpreter) Evaluate(expr Expr) (any, error) {
 	return expr.Accept(i)
 }
@@ -190,21 +607,19 @@ func (i *Interpreter) VisitPrintStmt(stmt *PrintStmt) error {
 	if err != nil {
 		return err
 	}
-	fmt.Println(value)
+	fmt.Fprintln(i.Stdout, stringify(value))
 	return nil
 }
 
 func (i *Interpreter) VisitVarDeclStmt(stmt *VarDeclStmt) error {
-	if stmt.Initializer == nil {
-		i.Env.Define(stmt.Name.Lexeme, nil)
-		return nil
-	}
-	value, err := i.Evaluate(stmt.Initializer)
-	if err != nil {
-		return err
+	var value any
+	if stmt.Initializer != nil {
+		var err error
+		if value, err = i.Evaluate(stmt.Initializer); err != nil {
+			return err
+		}
 	}
-	i.Env.Define(stmt.Name.Lexeme, value)
-	return nil
+	return i.Env.(*Environment).declare(stmt.Name, value, stmt.Const)
 }
 
 func (i *Interpreter) VisitBlockStmt(stmt *BlockStmt) error {
@@ -216,6 +631,7 @@ func (i *Interpreter) VisitIfStmt(stmt *IfStmt) error {
 	if err != nil {
 		return err
 	}
+	i.Coverage.Branch(stmt, isTruthy(condition))
 	if isTruthy(condition) {
 		return i.Execute(stmt.ThenBranch)
 	}
@@ -231,6 +647,7 @@ func (i *Interpreter) VisitWhileStmt(stmt *WhileStmt) error {
 		if err != nil {
 			return err
 		}
+		i.Coverage.Branch(stmt, isTruthy(condition))
 		if !isTruthy(condition) {
 			break
 		}
@@ -238,15 +655,192 @@ func (i *Interpreter) VisitWhileStmt(stmt *WhileStmt) error {
 			if err == ErrBreak {
 				break
 			}
-			if err == ErrContinue {
-				continue
+			if err != ErrContinue {
+				return err
 			}
-			return err
 		}
+		if stmt.Increment != nil {
+			if _, err := i.Evaluate(stmt.Increment); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (i *Interpreter) VisitMatchStmt(stmt *MatchStmt) error {
+	subject, err := i.Evaluate(stmt.Subject)
+	if err != nil {
+		return err
+	}
+	for _, arm := range stmt.Arms {
+		env := NewEnvironment(i.Env)
+		matched := matchPattern(arm.Pattern, subject, env)
+		if matched && arm.Guard != nil {
+			guard, err := i.evaluateIn(arm.Guard, env)
+			if err != nil {
+				return err
+			}
+			matched = isTruthy(guard)
+		}
+		i.Coverage.Branch(arm, matched)
+		if matched {
+			return i.ExecuteBlock([]Stmt{arm.Body}, env)
+		}
+	}
+	return nil
+}
+
+// evaluateIn evaluates expr in env instead of the current environment.
+func (i *Interpreter) evaluateIn(expr Expr, env Env) (any, error) {
+	previous := i.Env
+	i.Env = env
+	defer func() { i.Env = previous }()
+	return i.Evaluate(expr)
+}
+
+// matchPattern reports whether value matches pattern, defining the names
+// the pattern binds in env.
+func matchPattern(pattern Pattern, value any, env Env) bool {
+	switch p := pattern.(type) {
+	case *LiteralPattern:
+		return isEqual(p.Value, value)
+	case *RangePattern:
+		return inRange(value, p.Low, p.High)
+	case *BindingPattern:
+		if p.Name.Lexeme != "_" {
+			env.Define(p.Name.Lexeme, value)
+		}
+		return true
+	case *ListPattern:
+		list, ok := value.(*List)
+		if !ok {
+			return false
+		}
+		elements := list.Values()
+		if len(elements) < len(p.Elements) || p.Rest == nil && len(elements) != len(p.Elements) {
+			return false
+		}
+		for n, element := range p.Elements {
+			if !matchPattern(element, elements[n], env) {
+				return false
+			}
+		}
+		if p.Rest != nil && p.Rest.Lexeme != "_" {
+			env.Define(p.Rest.Lexeme, NewList(elements[len(p.Elements):]))
+		}
+		return true
+	}
+	return false
+}
+
+// inRange reports whether value is a number or a string between low and
+// high, inclusive.
+func inRange(value, low, high any) bool {
+	if isNumber(value) && isNumber(low) && isNumber(high) {
+		l, lOk := compareNumbers(low, value)
+		h, hOk := compareNumbers(value, high)
+		return lOk && hOk && l <= 0 && h <= 0
+	}
+	s, sOk := value.(string)
+	l, lOk := low.(string)
+	h, hOk := high.(string)
+	return sOk && lOk && hOk && l <= s && s <= h
+}
+
+func (i *Interpreter) VisitSpawnStmt(stmt *SpawnStmt) error {
+	callable, arguments, skipped, err := i.callee(stmt.Call)
+	if err != nil || skipped {
+		return err
 	}
+	i.fibers.spawn(i, callable, arguments, stmt.Call.Paren)
 	return nil
 }
 
+func (i *Interpreter) VisitYieldStmt(stmt *YieldStmt) error {
+	value, err := i.Evaluate(stmt.Value)
+	if err != nil {
+		return err
+	}
+	return i.generator.yield(value)
+}
+
+func (i *Interpreter) VisitForInStmt(stmt *ForInStmt) error {
+	iterable, err := i.Evaluate(stmt.Iterable)
+	if err != nil {
+		return err
+	}
+	next, err := i.iterator(stmt, iterable)
+	if err != nil {
+		return err
+	}
+	if generator, ok := iterable.(*Generator); ok {
+		defer generator.Close() // when the loop ends early
+	}
+	for {
+		value, done, err := next()
+		if err != nil {
+			return err
+		}
+		i.Coverage.Branch(stmt, !done)
+		if done {
+			return nil
+		}
+		env := NewEnvironment(i.Env)
+		env.Define(stmt.Name.Lexeme, value)
+		if err := i.ExecuteBlock([]Stmt{stmt.Body}, env); err != nil {
+			if err == ErrBreak {
+				return nil
+			}
+			if err != ErrContinue {
+				return err
+			}
+		}
+	}
+}
+
+// iterator returns a function returning the successive values a for-in
+// loop over value iterates, until done.
+func (i *Interpreter) iterator(stmt *ForInStmt, value any) (next func() (_ any, done bool, _ error), _ error) {
+	n := 0
+	switch value := value.(type) {
+	case *List:
+		return func() (any, bool, error) {
+			if n >= value.Len() {
+				return nil, true, nil
+			}
+			n++
+			return value.Get(n - 1), false, nil
+		}, nil
+	case string:
+		runes := []rune(value)
+		return func() (any, bool, error) {
+			if n >= len(runes) {
+				return nil, true, nil
+			}
+			n++
+			return string(runes[n-1]), false, nil
+		}, nil
+	case *Channel:
+		return func() (any, bool, error) {
+			value, ok, err := value.receive(i.turn)
+			if err != nil {
+				return nil, false, nativeError(err, stmt.Keyword.Line)
+			}
+			return value, !ok, nil
+		}, nil
+	case *Generator:
+		return func() (any, bool, error) {
+			value, done, err := value.Next(i)
+			if err == ErrGeneratorRunning {
+				return nil, false, Error(stmt.Keyword.Line, "generator is already running.")
+			}
+			return value, done, err
+		}, nil
+	}
+	return nil, Error(stmt.Keyword.Line, fmt.Sprintf("value of type '%T' can't be iterated.", value))
+}
+
 var ErrBreak = errors.New("break")
 
 func (i *Interpreter) VisitBreakStmt(*BreakStmt) error {
@@ -267,7 +861,40 @@ func (r *ReturnValue) Error() string {
 	return "return"
 }
 
+// Exit is returned by the exit native. It unwinds the interpreter like any
+// other error, so the cleanup of every block and call still runs, and asks
+// the host to end the process with Code.
+type Exit struct {
+	Code int
+}
+
+func (e *Exit) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// TailCall is returned instead of a ReturnValue by the return of a call, for
+// the function returning to make the call once its own body is done.
+type TailCall struct {
+	Callable  Callable
+	Arguments []any
+	Paren     Token
+}
+
+func (t *TailCall) Error() string {
+	return "return"
+}
+
 func (i *Interpreter) VisitReturnStmt(stmt *ReturnStmt) (err error) {
+	if call, ok := stmt.Value.(*CallExpr); ok {
+		callable, arguments, skipped, err := i.callee(call)
+		if err != nil {
+			return err
+		}
+		if skipped {
+			return &ReturnValue{}
+		}
+		return &TailCall{callable, arguments, call.Paren}
+	}
 	var value any
 	if stmt.Value != nil {
 		value, err = i.Evaluate(stmt.Value)
@@ -279,8 +906,7 @@ func (i *Interpreter) VisitReturnStmt(stmt *ReturnStmt) (err error) {
 }
 
 func (i *Interpreter) VisitFunctionStmt(stmt *FunctionStmt) error {
-	i.Env.Define(stmt.Name.Lexeme, NewFunction(i.Env, stmt))
-	return nil
+	return i.Env.(*Environment).declare(stmt.Name, NewFunction(i.Env, stmt), false)
 }
 
 func (i *Interpreter) ExecuteBlock(statements []Stmt, env Env) error {
@@ -296,10 +922,19 @@ func (i *Interpreter) ExecuteBlock(statements []Stmt, env Env) error {
 }
 
 func (i *Interpreter) Execute(stmt Stmt) error {
+	if err := i.fibers.exiting(); err != nil {
+		return err // another fiber called exit()
+	}
+	i.turn.step()
+	i.Coverage.Statement(stmt)
+	i.Profiler.Statement(stmt)
 	return stmt.Accept(i)
 }
 
+// Interpret runs program on the event loop, after the fibers ready before.
 func (i *Interpreter) Interpret(program Program) error {
+	leave := i.turn.enter()
+	defer leave()
 	for _, stmt := range program {
 		if err := i.Execute(stmt); err != nil {
 			return err
@@ -308,18 +943,69 @@ func (i *Interpreter) Interpret(program Program) error {
 	return nil
 }
 
+// Locals returns a copy of the depths of the local variables resolved so
+// far, by the expressions that refer to them.
+func (i *Interpreter) Locals() map[Expr]int {
+	if i.localsMu != nil {
+		i.localsMu.RLock()
+		defer i.localsMu.RUnlock()
+	}
+	return maps.Clone(i.locals)
+}
+
+// Yield lets the other fibers run. Natives call it while they wait for
+// something other than a future, which would hold up every fiber otherwise.
+func (i *Interpreter) Yield() {
+	if i.turn.holds > 0 {
+		i.turn.loop.yield()
+	}
+}
+
+// Resolve records the depth of a local variable, for the resolver.
 func (i *Interpreter) Resolve(expr Expr, depth int) error {
-	i.Locals[expr] = depth
+	if i.compiled {
+		return errors.New("can't resolve code on the interpreter of a compiled script")
+	}
+	if i.localsMu != nil {
+		i.localsMu.Lock()
+		defer i.localsMu.Unlock()
+	}
+	i.locals[expr] = depth
 	return nil
 }
 
+// depth returns the depth of the local variable expr refers to, if it isn't
+// a global.
+func (i *Interpreter) depth(expr Expr) (int, bool) {
+	if i.localsMu != nil {
+		i.localsMu.RLock()
+		defer i.localsMu.RUnlock()
+	}
+	depth, ok := i.locals[expr]
+	return depth, ok
+}
+
 func (i *Interpreter) LookupVariable(name Token, expr Expr) (any, error) {
-	if depth, ok := i.Locals[expr]; ok {
+	if depth, ok := i.depth(expr); ok {
 		return i.Env.GetAt(depth, name.Lexeme)
 	}
 	return i.Globals.Get(name)
 }
 
+func (i *Interpreter) AssignVariable(name Token, expr Expr, value any) error {
+	if depth, ok := i.depth(expr); ok {
+		return i.Env.AssignAt(depth, name, value)
+	}
+	return i.Globals.Assign(name, value)
+}
+
+func stringify(value any) string {
+	if value == nil {
+		return "nil"
+	}
+	return fmt.Sprint(value)
+}
+
 func isTruthy(value any) bool {
 	if value == nil {
 		return false
@@ -337,6 +1023,10 @@ func isEqual(a, b any) bool {
 	if a == nil || b == nil {
 		return false
 	}
+	if isNumber(a) && isNumber(b) {
+		c, ok := compareNumbers(a, b)
+		return ok && c == 0
+	}
 	return a == b
 }
 
@@ -345,26 +1035,28 @@ func isBool(value any) bool {
 	return ok
 }
 
-func isFloat(value any) bool {
-	_, ok := value.(float64)
-	return ok
-}
-
 func isString(value any) bool {
 	_, ok := value.(string)
 	return ok
 }
 
 func checkNumberOperand(operator Token, operand any) error {
-	if isFloat(operand) {
+	if isNumber(operand) {
 		return nil
 	}
 	return Error(operator.Line, fmt.Sprintf("operand must be a number, got %T.", operand))
 }
 
 func checkNumberOperands(operator Token, left, right any) error {
-	if isFloat(left) && isFloat(right) {
+	if isNumber(left) && isNumber(right) {
 		return nil
 	}
 	return Error(operator.Line, fmt.Sprintf("operands must be numbers, got %T and %T.", left, right))
 }
+
+func checkIntegerOperands(operator Token, left, right any) error {
+	if isInteger(left) && isInteger(right) {
+		return nil
+	}
+	return Error(operator.Line, fmt.Sprintf("operands must be integers, got %T and %T.", left, right))
+}
@@ -0,0 +1,86 @@
+package glox
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// List is the runtime value of list literals like `[1, 2, 3]`. Fibers can
+// share a list, so the interpreter goes through its methods, which lock it.
+// Hosts may use Elements directly while no script can reach the list.
+type List struct {
+	Elements []any
+
+	mu     sync.RWMutex
+	frozen bool // set by freeze, a frozen list can't be modified
+}
+
+func NewList(elements []any) *List {
+	return &List{Elements: elements}
+}
+
+func (l *List) String() string {
+	var elements []string
+	for _, element := range l.Values() {
+		elements = append(elements, stringify(element))
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+func (l *List) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.Elements)
+}
+
+// Get returns the element at n, which must be in range.
+func (l *List) Get(n int) any {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.Elements[n]
+}
+
+// Set replaces the element at n, which must be in range, unless the list is
+// frozen, and reports whether it did.
+func (l *List) Set(n int, value any) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.frozen {
+		return false
+	}
+	l.Elements[n] = value
+	return true
+}
+
+// Values returns a copy of the elements.
+func (l *List) Values() []any {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return slices.Clone(l.Elements)
+}
+
+func (l *List) Frozen() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.frozen
+}
+
+// Freeze makes the list and every list nested in it immutable.
+func (l *List) Freeze() {
+	l.mu.Lock()
+	if l.frozen {
+		l.mu.Unlock()
+		return
+	}
+	l.frozen = true
+	elements := slices.Clone(l.Elements)
+	l.mu.Unlock()
+	// the nested lists are frozen without holding the lock, since a list can
+	// contain itself
+	for _, element := range elements {
+		if list, ok := element.(*List); ok {
+			list.Freeze()
+		}
+	}
+}
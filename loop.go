@@ -0,0 +1,105 @@
+package glox
+
+import "sync"
+
+// timeSlice is the number of statements a fiber runs before it lets the
+// other ready fibers run.
+const timeSlice = 1000
+
+// eventLoop runs the fibers of a program one at a time. The fiber holding
+// the turn runs until it waits, for a channel or a future, or until it ran
+// a time slice, and hands the turn to the next ready fiber. A fiber waiting
+// for a future is parked until the host completes it, and queued again
+// then, so slow host services only hold up the fibers awaiting them. Host
+// natives that block, unlike the async ones, hold up every fiber.
+type eventLoop struct {
+	mu      sync.Mutex
+	running bool            // a fiber holds the turn
+	ready   []chan struct{} // the fibers waiting for the turn, in order
+}
+
+// acquire waits until the calling fiber gets the turn.
+func (l *eventLoop) acquire() {
+	l.mu.Lock()
+	if !l.running {
+		l.running = true
+		l.mu.Unlock()
+		return
+	}
+	turn := make(chan struct{})
+	l.ready = append(l.ready, turn)
+	l.mu.Unlock()
+	<-turn
+}
+
+// release hands the turn to the next ready fiber, if any.
+func (l *eventLoop) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.ready) == 0 {
+		l.running = false
+		return
+	}
+	close(l.ready[0]) // running stays true for the next fiber
+	l.ready = l.ready[1:]
+}
+
+// yield lets the ready fibers run before the calling one goes on.
+func (l *eventLoop) yield() {
+	l.mu.Lock()
+	idle := len(l.ready) == 0
+	l.mu.Unlock()
+	if !idle {
+		l.release()
+		l.acquire()
+	}
+}
+
+// turn is the share of a fiber in the event loop. The body of a generator
+// uses the turn of its consumer, which waits while it runs.
+type turn struct {
+	loop  *eventLoop
+	holds int // the nested runs of the fiber, it holds the turn while > 0
+	steps int // the statements run in the current time slice
+}
+
+// enter takes the turn unless the fiber holds it already, and returns the
+// function that gives it back.
+func (t *turn) enter() (leave func()) {
+	if t.holds++; t.holds == 1 {
+		t.loop.acquire()
+		t.steps = 0
+	}
+	return func() {
+		if t.holds--; t.holds == 0 {
+			t.loop.release()
+		}
+	}
+}
+
+// pause hands the turn over while the fiber waits, and reports whether it
+// did: a host calling a Lox function outside of Interpret doesn't hold it.
+func (t *turn) pause() bool {
+	if t.holds == 0 {
+		return false
+	}
+	t.loop.release()
+	return true
+}
+
+// resume takes the turn back after a pause.
+func (t *turn) resume() {
+	t.loop.acquire()
+	t.steps = 0
+}
+
+// step counts a statement, and yields at the end of the time slice.
+func (t *turn) step() {
+	if t.holds == 0 {
+		return
+	}
+	if t.steps++; t.steps >= timeSlice {
+		t.loop.yield()
+		t.steps = 0
+	}
+}
@@ -0,0 +1,138 @@
+package glox
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+)
+
+// DefineMath installs the math library in env. The random numbers come from
+// a generator seeded with seed, so embedders can fix it to get reproducible
+// runs:
+//
+//	env := glox.DefaultGlobals()
+//	glox.DefineMath(env, 42)
+func DefineMath(env Env, seed uint64) {
+	env.Define("pi", math.Pi)
+	env.Define("e", math.E)
+
+	for name, fn := range map[string]func(float64) float64{
+		"floor": math.Floor,
+		"ceil":  math.Ceil,
+		"round": math.Round,
+		"abs":   math.Abs,
+		"sqrt":  math.Sqrt,
+		"exp":   math.Exp,
+		"log":   math.Log,
+		"sin":   math.Sin,
+		"cos":   math.Cos,
+		"tan":   math.Tan,
+		"asin":  math.Asin,
+		"acos":  math.Acos,
+		"atan":  math.Atan,
+	} {
+		env.Define(name, NewNativeFunction(name, 1,
+			func(_ *Interpreter, args []any) (any, error) {
+				x, err := numberArg(name, args[0])
+				if err != nil {
+					return nil, err
+				}
+				return fn(x), nil
+			},
+		))
+	}
+
+	for name, fn := range map[string]func(float64, float64) float64{
+		"pow":   math.Pow,
+		"atan2": math.Atan2,
+	} {
+		env.Define(name, NewNativeFunction(name, 2,
+			func(_ *Interpreter, args []any) (any, error) {
+				x, err := numberArg(name, args[0])
+				if err != nil {
+					return nil, err
+				}
+				y, err := numberArg(name, args[1])
+				if err != nil {
+					return nil, err
+				}
+				return fn(x, y), nil
+			},
+		))
+	}
+
+	for name, sign := range map[string]int{"min": -1, "max": 1} {
+		env.Define(name, NewVariadicNativeFunction(name, 1, Unlimited,
+			func(_ *Interpreter, args []any) (any, error) {
+				result := args[0]
+				for _, arg := range args {
+					if !isNumber(arg) {
+						return nil, fmt.Errorf("%s expects a number, got %T", name, arg)
+					}
+					c, ok := compareNumbers(arg, result)
+					if !ok {
+						return math.NaN(), nil
+					}
+					if c == sign {
+						result = arg
+					}
+				}
+				return result, nil
+			},
+		))
+	}
+
+	env.Define("isNaN", NewNativeFunction("isNaN", 1,
+		func(_ *Interpreter, args []any) (any, error) {
+			x, err := numberArg("isNaN", args[0])
+			if err != nil {
+				return nil, err
+			}
+			return math.IsNaN(x), nil
+		},
+	))
+	env.Define("isInf", NewNativeFunction("isInf", 1,
+		func(_ *Interpreter, args []any) (any, error) {
+			x, err := numberArg("isInf", args[0])
+			if err != nil {
+				return nil, err
+			}
+			return math.IsInf(x, 0), nil
+		},
+	))
+
+	random := rand.New(rand.NewPCG(seed, seed))
+	var mu sync.Mutex // the fibers of a program share the generator
+	env.Define("random", NewNativeFunction("random", 0,
+		func(*Interpreter, []any) (any, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return random.Float64(), nil
+		},
+	))
+	env.Define("randomInt", NewNativeFunction("randomInt", 2,
+		func(_ *Interpreter, args []any) (any, error) {
+			low, err := integerArg("randomInt", args[0])
+			if err != nil {
+				return nil, err
+			}
+			high, err := integerArg("randomInt", args[1])
+			if err != nil {
+				return nil, err
+			}
+			if low > high {
+				return nil, fmt.Errorf("randomInt expects min <= max, got %d and %d", low, high)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			// the span can exceed the int64 range, and wraps to 0 when it's
+			// the whole uint64 range
+			span := uint64(high) - uint64(low) + 1
+			if span == 0 {
+				return int64(random.Uint64()), nil
+			}
+			return low + int64(random.Uint64N(span)), nil
+		},
+	))
+}
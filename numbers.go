@@ -0,0 +1,311 @@
+package glox
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Numbers are integers (int64), floats (float64), big integers (*big.Int)
+// or decimals (Decimal). Integer arithmetic is exact and only turns into
+// float arithmetic when the result overflows; an operation mixing an
+// integer and a float gives a float, and division always does, as does
+// raising an integer to a negative power. Like in Go, the result of % has
+// the sign of the dividend.
+//
+// Big integers and decimals are exact too. Mixed with integers they give
+// big integers or decimals, and the division of big integers gives a
+// decimal. Mixing them with floats is an error, since the result couldn't
+// be exact.
+
+func isNumber(value any) bool {
+	switch value.(type) {
+	case int64, float64, *big.Int, Decimal:
+		return true
+	}
+	return false
+}
+
+// isExact reports whether value is a big integer or a decimal.
+func isExact(value any) bool {
+	switch value.(type) {
+	case *big.Int, Decimal:
+		return true
+	}
+	return false
+}
+
+// toFloat converts a number to the nearest float.
+func toFloat(value any) float64 {
+	switch x := value.(type) {
+	case int64:
+		return float64(x)
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(x).Float64()
+		return f
+	case Decimal:
+		f, _ := x.rat.Float64()
+		return f
+	}
+	return value.(float64)
+}
+
+// toInteger converts a number with no fractional part to an integer.
+func toInteger(value any) (int64, bool) {
+	switch x := value.(type) {
+	case int64:
+		return x, true
+	case float64:
+		if x == math.Trunc(x) && x >= math.MinInt64 && x < math.MaxInt64 {
+			return int64(x), true
+		}
+	case *big.Int:
+		if x.IsInt64() {
+			return x.Int64(), true
+		}
+	case Decimal:
+		if x.rat.IsInt() && x.rat.Num().IsInt64() {
+			return x.rat.Num().Int64(), true
+		}
+	}
+	return 0, false
+}
+
+// toRat converts a number to an exact fraction. It fails for the floats
+// that aren't finite.
+func toRat(value any) (*big.Rat, bool) {
+	if f, ok := value.(float64); ok {
+		rat := new(big.Rat).SetFloat64(f)
+		return rat, rat != nil
+	}
+	return toDecimal(value).rat, true
+}
+
+// arithmetic applies the +, -, *, /, % or ** operator to two numbers.
+func arithmetic(operator TokenType, a, b any) (any, error) {
+	if isExact(a) || isExact(b) {
+		_, aFloat := a.(float64)
+		_, bFloat := b.(float64)
+		if aFloat || bFloat {
+			return nil, fmt.Errorf("can't mix a float with an exact number, convert it with decimal() first")
+		}
+		_, aDecimal := a.(Decimal)
+		_, bDecimal := b.(Decimal)
+		if aDecimal || bDecimal {
+			return decimalArithmetic(operator, toDecimal(a), toDecimal(b))
+		}
+		return bigArithmetic(operator, toBig(a), toBig(b))
+	}
+
+	x, xInt := a.(int64)
+	y, yInt := b.(int64)
+	if xInt && yInt {
+		switch operator {
+		case Plus:
+			if sum := x + y; (sum > x) == (y > 0) {
+				return sum, nil
+			}
+		case Minus:
+			if difference := x - y; (difference < x) == (y > 0) {
+				return difference, nil
+			}
+		case Star:
+			if product, ok := multiply(x, y); ok {
+				return product, nil
+			}
+		case Percent:
+			if y == 0 {
+				return nil, fmt.Errorf("integer modulo by zero")
+			}
+			return x % y, nil
+		case StarStar:
+			if power, ok := power(x, y); ok {
+				return power, nil
+			}
+		}
+	}
+	fx, fy := toFloat(a), toFloat(b)
+	switch operator {
+	case Plus:
+		return fx + fy, nil
+	case Minus:
+		return fx - fy, nil
+	case Star:
+		return fx * fy, nil
+	case Percent:
+		return math.Mod(fx, fy), nil
+	case StarStar:
+		return math.Pow(fx, fy), nil
+	default:
+		return fx / fy, nil
+	}
+}
+
+// multiply multiplies two integers, failing if the product overflows.
+func multiply(x, y int64) (int64, bool) {
+	product := x * y
+	if x == 0 || (product/x == y && !(x == -1 && y == math.MinInt64)) {
+		return product, true
+	}
+	return 0, false
+}
+
+// power raises an integer to a non-negative integer exponent, failing if
+// the exponent is negative or the result overflows.
+func power(x, y int64) (int64, bool) {
+	if y < 0 {
+		return 0, false
+	}
+	result := int64(1)
+	for ; y > 0; y >>= 1 {
+		var ok bool
+		if y&1 == 1 {
+			if result, ok = multiply(result, x); !ok {
+				return 0, false
+			}
+		}
+		if y > 1 {
+			if x, ok = multiply(x, x); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
+
+// bitwise applies the &, |, ^, << or >> operator to two integers or big
+// integers. Integers behave like 64-bit two's complement numbers, so their
+// shifts can overflow.
+func bitwise(operator TokenType, a, b any) (any, error) {
+	x, xInt := a.(int64)
+	y, yInt := b.(int64)
+	if xInt && yInt {
+		switch operator {
+		case Ampersand:
+			return x & y, nil
+		case Pipe:
+			return x | y, nil
+		case Caret:
+			return x ^ y, nil
+		}
+		if y < 0 {
+			return nil, fmt.Errorf("negative shift count %d", y)
+		}
+		if operator == LessLess {
+			return x << y, nil
+		}
+		return x >> y, nil
+	}
+
+	bx, by := toBig(a), toBig(b)
+	switch operator {
+	case Ampersand:
+		return new(big.Int).And(bx, by), nil
+	case Pipe:
+		return new(big.Int).Or(bx, by), nil
+	case Caret:
+		return new(big.Int).Xor(bx, by), nil
+	}
+	if by.Sign() < 0 || !by.IsUint64() {
+		return nil, fmt.Errorf("invalid shift count %s", by)
+	}
+	if operator == LessLess {
+		return new(big.Int).Lsh(bx, uint(by.Uint64())), nil
+	}
+	return new(big.Int).Rsh(bx, uint(by.Uint64())), nil
+}
+
+func isInteger(value any) bool {
+	switch value.(type) {
+	case int64, *big.Int:
+		return true
+	}
+	return false
+}
+
+func negate(value any) any {
+	switch x := value.(type) {
+	case int64:
+		if x != math.MinInt64 {
+			return -x
+		}
+	case *big.Int:
+		return new(big.Int).Neg(x)
+	case Decimal:
+		return Decimal{new(big.Rat).Neg(x.rat), x.scale}
+	}
+	return -toFloat(value)
+}
+
+// complement flips the bits of an integer or a big integer.
+func complement(value any) any {
+	if x, ok := value.(int64); ok {
+		return ^x
+	}
+	return new(big.Int).Not(value.(*big.Int))
+}
+
+// compareNumbers returns -1, 0 or 1 as a is less than, equal to or greater
+// than b. Numbers of different types are compared exactly, without
+// rounding either. ok is false if either number is NaN, which isn't ordered.
+func compareNumbers(a, b any) (c int, ok bool) {
+	if isExact(a) || isExact(b) {
+		x, xOk := toRat(a)
+		y, yOk := toRat(b)
+		switch {
+		case xOk && yOk:
+			return x.Cmp(y), true
+		case !xOk:
+			return compareInfinite(toFloat(a))
+		default:
+			c, ok := compareInfinite(toFloat(b))
+			return -c, ok
+		}
+	}
+
+	x, xInt := a.(int64)
+	y, yInt := b.(int64)
+	switch {
+	case xInt && yInt:
+		return cmp.Compare(x, y), true
+	case xInt:
+		return compareMixed(x, b.(float64))
+	case yInt:
+		c, ok := compareMixed(y, a.(float64))
+		return -c, ok
+	}
+	fx, fy := a.(float64), b.(float64)
+	if math.IsNaN(fx) || math.IsNaN(fy) {
+		return 0, false
+	}
+	return cmp.Compare(fx, fy), true
+}
+
+func compareMixed(x int64, y float64) (int, bool) {
+	if math.IsNaN(y) {
+		return 0, false
+	}
+	if integer, ok := toInteger(y); ok {
+		return cmp.Compare(x, integer), true
+	}
+	return cmp.Compare(float64(x), y), true
+}
+
+// compareInfinite compares an infinite or NaN float with a finite number.
+func compareInfinite(f float64) (int, bool) {
+	if math.IsNaN(f) {
+		return 0, false
+	}
+	return cmp.Compare(f, 0), true
+}
+
+// parseNumber parses the text of a number as an integer if it can, or as
+// a float.
+func parseNumber(text string) (any, error) {
+	if x, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return x, nil
+	}
+	return strconv.ParseFloat(text, 64)
+}
@@ -7,6 +7,7 @@ type Parser struct {
 	Current       int
 	LoopDepth     int
 	CallableDepth int
+	Yielded       bool // whether the function being parsed has a yield
 }
 
 func NewParser(tokens []Token) *Parser {
@@ -35,7 +36,7 @@ func (p *Parser) Declaration() (Stmt, error) {
 	if p.Match(Fun) {
 		return p.FunDeclaration("function")
 	}
-	if p.Match(Var) {
+	if p.Match(Var, Const) {
 		return p.VarDeclaration()
 	}
 	return p.Statement()
@@ -50,7 +51,7 @@ func (p *Parser) FunDeclaration(kind string) (_ Stmt, err error) {
 	if !p.Match(LeftParen) {
 		return nil, p.Error(p.Peek(), "expect '(' after function name")
 	}
-	var parameters []Token
+	var parameters ParamList
 	if !p.Check(RightParen) {
 		parameters, err = p.Parameters()
 		if err != nil {
@@ -63,30 +64,69 @@ func (p *Parser) FunDeclaration(kind string) (_ Stmt, err error) {
 	if !p.Match(LeftBrace) {
 		return nil, p.Error(p.Peek(), "expect '{' before "+kind+" body")
 	}
-	p.CallableDepth++
-	body, err := p.Block()
+	body, generator, err := p.FunctionBody()
 	if err != nil {
 		return nil, err
 	}
-	p.CallableDepth--
 	return &FunctionStmt{
-		Name:   name,
-		Params: parameters,
-		Body:   body.Statements,
+		Name:      name,
+		ParamList: parameters,
+		Body:      body,
+		Generator: generator,
 	}, nil
 }
 
-// Parameters -> IDENTIFIER ( "," IDENTIFIER )* ;
-func (p *Parser) Parameters() ([]Token, error) {
-	var parameters []Token
+// FunctionBody parses the block of a function and reports whether it's a
+// generator, a function with a yield of its own.
+func (p *Parser) FunctionBody() (_ []Stmt, generator bool, _ error) {
+	outer := p.Yielded
+	defer func() { p.Yielded = outer }()
+	p.Yielded = false
+	p.CallableDepth++
+	body, err := p.Block()
+	if err != nil {
+		return nil, false, err
+	}
+	p.CallableDepth--
+	return body.Statements, p.Yielded, nil
+}
+
+// Parameters -> Parameter ( "," Parameter )* ( "," "..." IDENTIFIER )?
+//
+//	| "..." IDENTIFIER ;
+//
+// Parameter -> IDENTIFIER ( "=" Expression )? ;
+func (p *Parser) Parameters() (parameters ParamList, _ error) {
 	for {
-		if len(parameters) >= 255 {
-			return nil, p.Error(p.Peek(), "can't have more than 255 parameters")
+		if len(parameters.Params) >= 255 {
+			return parameters, p.Error(p.Peek(), "can't have more than 255 parameters")
+		}
+		if p.Match(DotDotDot) {
+			if !p.Match(Identifier) {
+				return parameters, p.Error(p.Peek(), "expect parameter name after '...'")
+			}
+			rest := p.Previous()
+			parameters.Rest = &rest
+			if p.Check(Comma) {
+				return parameters, p.Error(p.Peek(), "the rest parameter must be the last one")
+			}
+			break
 		}
 		if !p.Match(Identifier) {
-			return nil, p.Error(p.Peek(), "expect parameter name")
+			return parameters, p.Error(p.Peek(), "expect parameter name")
+		}
+		name := p.Previous()
+		var value Expr
+		if p.Match(Equal) {
+			var err error
+			if value, err = p.Expression(); err != nil {
+				return parameters, err
+			}
+		} else if len(parameters.Defaults) > 0 && parameters.Defaults[len(parameters.Defaults)-1] != nil {
+			return parameters, p.Error(name, "a parameter without a default can't follow one with a default")
 		}
-		parameters = append(parameters, p.Previous())
+		parameters.Params = append(parameters.Params, name)
+		parameters.Defaults = append(parameters.Defaults, value)
 		if !p.Match(Comma) {
 			break
 		}
@@ -94,8 +134,11 @@ func (p *Parser) Parameters() ([]Token, error) {
 	return parameters, nil
 }
 
-// VarDeclaration -> "var" IDENTIFIER ( "=" Expression )? ";" ;
+// VarDeclaration -> "var" IDENTIFIER ( "=" Expression )? ";"
+//
+//	| "const" IDENTIFIER "=" Expression ";" ;
 func (p *Parser) VarDeclaration() (_ Stmt, err error) {
+	constant := p.Previous().Type == Const
 	if !p.Match(Identifier) {
 		return nil, p.Error(p.Peek(), "expect variable name")
 	}
@@ -106,11 +149,13 @@ func (p *Parser) VarDeclaration() (_ Stmt, err error) {
 		if err != nil {
 			return nil, err
 		}
+	} else if constant {
+		return nil, p.Error(p.Peek(), "expect '=' after constant name")
 	}
 	if !p.Match(Semicolon) {
 		return nil, p.Error(p.Peek(), "expect ';' after variable declaration")
 	}
-	return &VarDeclStmt{Name: name, Initializer: initializer}, nil
+	return &VarDeclStmt{Name: name, Initializer: initializer, Const: constant}, nil
 }
 
 // Statement -> IfStatement
@@ -122,6 +167,9 @@ func (p *Parser) VarDeclaration() (_ Stmt, err error) {
 //	    | BreakStatement
 //	    | ContinueStatement
 //	    | ReturnStatement
+//	    | YieldStatement
+//	    | SpawnStatement
+//	    | MatchStatement
 //			| ExpressionStatement ;
 func (p *Parser) Statement() (Stmt, error) {
 	if p.Match(If) {
@@ -148,11 +196,21 @@ func (p *Parser) Statement() (Stmt, error) {
 	if p.Match(Return) {
 		return p.ReturnStatement()
 	}
+	if p.Match(Yield) {
+		return p.YieldStatement()
+	}
+	if p.Match(Spawn) {
+		return p.SpawnStatement()
+	}
+	if p.Match(Match) {
+		return p.MatchStatement()
+	}
 	return p.ExpressionStatement()
 }
 
 // IfStatement -> "if" "(" Expression ")" Statement ( "else" Statement )? ;
 func (p *Parser) IfStatement() (Stmt, error) {
+	keyword := p.Previous()
 	if !p.Match(LeftParen) {
 		return nil, p.Error(p.Peek(), "expect '(' after 'if'")
 	}
@@ -175,6 +233,7 @@ func (p *Parser) IfStatement() (Stmt, error) {
 		}
 	}
 	return &IfStmt{
+		Keyword:    keyword,
 		Condition:  condition,
 		ThenBranch: thenBranch,
 		ElseBranch: elseBranch,
@@ -183,6 +242,7 @@ func (p *Parser) IfStatement() (Stmt, error) {
 
 // WhileStatement -> "while" "(" Expression ")" Statement ;
 func (p *Parser) WhileStatement() (Stmt, error) {
+	keyword := p.Previous()
 	if !p.Match(LeftParen) {
 		return nil, p.Error(p.Peek(), "expect '(' after 'while'")
 	}
@@ -202,16 +262,23 @@ func (p *Parser) WhileStatement() (Stmt, error) {
 	p.LoopDepth--
 
 	return &WhileStmt{
+		Keyword:   keyword,
 		Condition: condition,
 		Body:      body,
 	}, nil
 }
 
-// ForStatement -> "for" "(" ( VarDeclaration | ExpressionStatement | ";" ) Expression? ";" Expression? ")" Statement ;
+// ForStatement -> "for" "(" ( VarDeclaration | ExpressionStatement | ";" ) Expression? ";" Expression? ")" Statement
+//
+//	| "for" "(" "var" IDENTIFIER "in" Expression ")" Statement ;
 func (p *Parser) ForStatement() (_ Stmt, err error) {
+	keyword := p.Previous()
 	if !p.Match(LeftParen) {
 		return nil, p.Error(p.Peek(), "expect '(' after 'for'")
 	}
+	if p.Check(Var) && p.CheckNext(Identifier) && p.Tokens[p.Current+2].Type == In {
+		return p.ForInStatement(keyword)
+	}
 	var initializer Stmt
 	if p.Match(Semicolon) {
 		initializer = nil // not needed, but explicit
@@ -255,22 +322,16 @@ func (p *Parser) ForStatement() (_ Stmt, err error) {
 	p.LoopDepth--
 
 	// desugar for loop into while loop
-	if increment != nil {
-		body = &BlockStmt{
-			Statements: []Stmt{
-				body,
-				&ExpressionStmt{Expr: increment},
-			},
-		}
-	}
-
 	body = &WhileStmt{
+		Keyword:   keyword,
 		Condition: condition,
 		Body:      body,
+		Increment: increment,
 	}
 
 	if initializer != nil {
 		body = &BlockStmt{
+			Start: keyword,
 			Statements: []Stmt{
 				initializer,
 				body,
@@ -281,8 +342,30 @@ func (p *Parser) ForStatement() (_ Stmt, err error) {
 	return body, nil
 }
 
+// ForInStatement parses the rest of a for-in loop, after its "(".
+func (p *Parser) ForInStatement(keyword Token) (Stmt, error) {
+	p.Advance() // the "var"
+	name := p.Advance()
+	p.Advance() // the "in"
+	iterable, err := p.Expression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.Match(RightParen) {
+		return nil, p.Error(p.Peek(), "expect ')' after for-in iterable")
+	}
+	p.LoopDepth++
+	body, err := p.Statement()
+	if err != nil {
+		return nil, err
+	}
+	p.LoopDepth--
+	return &ForInStmt{Keyword: keyword, Name: name, Iterable: iterable, Body: body}, nil
+}
+
 // PrintStatement -> "print" Expression ";" ;
 func (p *Parser) PrintStatement() (Stmt, error) {
+	keyword := p.Previous()
 	expr, err := p.Expression()
 	if err != nil {
 		return nil, err
@@ -290,7 +373,7 @@ func (p *Parser) PrintStatement() (Stmt, error) {
 	if !p.Match(Semicolon) {
 		return nil, p.Error(p.Peek(), "expect ';' after value")
 	}
-	return &PrintStmt{Expr: expr}, nil
+	return &PrintStmt{Keyword: keyword, Expr: expr}, nil
 }
 
 // ReturnStatement -> "return" Expression? ";" ;
@@ -312,8 +395,165 @@ func (p *Parser) ReturnStatement() (_ Stmt, err error) {
 	return &ReturnStmt{Keyword: keyword, Value: value}, nil
 }
 
+// YieldStatement -> "yield" Expression ";" ;
+func (p *Parser) YieldStatement() (Stmt, error) {
+	if p.CallableDepth == 0 {
+		return nil, p.Error(p.Previous(), "unexpected 'yield' outside a function")
+	}
+	keyword := p.Previous()
+	value, err := p.Expression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.Match(Semicolon) {
+		return nil, p.Error(p.Peek(), "expect ';' after yield value")
+	}
+	p.Yielded = true
+	return &YieldStmt{Keyword: keyword, Value: value}, nil
+}
+
+// SpawnStatement -> "spawn" Call ";" ;
+func (p *Parser) SpawnStatement() (Stmt, error) {
+	keyword := p.Previous()
+	expr, err := p.Expression()
+	if err != nil {
+		return nil, err
+	}
+	call, ok := expr.(*CallExpr)
+	if !ok {
+		return nil, p.Error(keyword, "expect a call after 'spawn'")
+	}
+	if !p.Match(Semicolon) {
+		return nil, p.Error(p.Peek(), "expect ';' after spawned call")
+	}
+	return &SpawnStmt{Keyword: keyword, Call: call}, nil
+}
+
+// MatchStatement -> "match" "(" Expression ")" "{" MatchArm* "}" ;
+func (p *Parser) MatchStatement() (Stmt, error) {
+	keyword := p.Previous()
+	if !p.Match(LeftParen) {
+		return nil, p.Error(p.Peek(), "expect '(' after 'match'")
+	}
+	subject, err := p.Expression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.Match(RightParen) {
+		return nil, p.Error(p.Peek(), "expect ')' after match subject")
+	}
+	if !p.Match(LeftBrace) {
+		return nil, p.Error(p.Peek(), "expect '{' before match arms")
+	}
+	var arms []*MatchArm
+	for !p.Check(RightBrace) && !p.IsAtEnd() {
+		arm, err := p.MatchArm()
+		if err != nil {
+			return nil, err
+		}
+		arms = append(arms, arm)
+	}
+	if !p.Match(RightBrace) {
+		return nil, p.Error(p.Peek(), "expect '}' after match arms")
+	}
+	return &MatchStmt{Keyword: keyword, Subject: subject, Arms: arms}, nil
+}
+
+// MatchArm -> "case" Pattern ( "if" Expression )? "=>" Statement ;
+func (p *Parser) MatchArm() (_ *MatchArm, err error) {
+	if !p.Match(Case) {
+		return nil, p.Error(p.Peek(), "expect 'case' before match arm")
+	}
+	arm := &MatchArm{Case: p.Previous()}
+	if arm.Pattern, err = p.Pattern(); err != nil {
+		return nil, err
+	}
+	if p.Match(If) {
+		if arm.Guard, err = p.Expression(); err != nil {
+			return nil, err
+		}
+	}
+	if !p.Match(Arrow) {
+		return nil, p.Error(p.Peek(), "expect '=>' after pattern")
+	}
+	if arm.Body, err = p.Statement(); err != nil {
+		return nil, err
+	}
+	return arm, nil
+}
+
+// Pattern -> PatternLiteral ( ".." PatternLiteral )? | IDENTIFIER | ListPattern ;
+func (p *Parser) Pattern() (Pattern, error) {
+	if p.Match(Identifier) {
+		return &BindingPattern{Name: p.Previous()}, nil
+	}
+	if p.Match(LeftBracket) {
+		return p.ListPattern()
+	}
+	literal, err := p.PatternLiteral()
+	if err != nil {
+		return nil, err
+	}
+	if !p.Match(DotDot) {
+		return literal, nil
+	}
+	dots := p.Previous()
+	high, err := p.PatternLiteral()
+	if err != nil {
+		return nil, err
+	}
+	return &RangePattern{Token: dots, Low: literal.Value, High: high.Value}, nil
+}
+
+// PatternLiteral -> "-"? NUMBER | STRING | "true" | "false" | "nil" ;
+func (p *Parser) PatternLiteral() (*LiteralPattern, error) {
+	switch {
+	case p.Match(Minus):
+		if !p.Match(Number) {
+			return nil, p.Error(p.Peek(), "expect number after '-' in pattern")
+		}
+		return &LiteralPattern{Token: p.Previous(), Value: negate(p.Previous().Literal)}, nil
+	case p.Match(Number, String):
+		return &LiteralPattern{Token: p.Previous(), Value: p.Previous().Literal}, nil
+	case p.Match(True):
+		return &LiteralPattern{Token: p.Previous(), Value: true}, nil
+	case p.Match(False):
+		return &LiteralPattern{Token: p.Previous(), Value: false}, nil
+	case p.Match(Nil):
+		return &LiteralPattern{Token: p.Previous(), Value: nil}, nil
+	}
+	return nil, p.Error(p.Peek(), "expect pattern")
+}
+
+// ListPattern -> "[" ( Pattern ( "," Pattern )* )? ( ","? "..." IDENTIFIER )? "]" ;
+func (p *Parser) ListPattern() (Pattern, error) {
+	pattern := &ListPattern{Bracket: p.Previous()}
+	for !p.Check(RightBracket) && !p.Check(DotDotDot) {
+		element, err := p.Pattern()
+		if err != nil {
+			return nil, err
+		}
+		pattern.Elements = append(pattern.Elements, element)
+		if !p.Match(Comma) {
+			break
+		}
+	}
+	if p.Match(DotDotDot) {
+		if !p.Match(Identifier) {
+			return nil, p.Error(p.Peek(), "expect name after '...'")
+		}
+		rest := p.Previous()
+		pattern.Rest = &rest
+	}
+	if !p.Match(RightBracket) {
+		return nil, p.Error(p.Peek(), "expect ']' after list pattern")
+	}
+	return pattern, nil
+}
+
 // ExpressionStatement -> Expression ";" ;
 func (p *Parser) ExpressionStatement() (Stmt, error) {
+	start := p.Peek()
 	expr, err := p.Expression()
 	if err != nil {
 		return nil, err
@@ -321,11 +561,12 @@ func (p *Parser) ExpressionStatement() (Stmt, error) {
 	if !p.Match(Semicolon) {
 		return nil, p.Error(p.Peek(), "expect ';' after expression")
 	}
-	return &ExpressionStmt{Expr: expr}, nil
+	return &ExpressionStmt{Start: start, Expr: expr}, nil
 }
 
 // Block -> "{" Declaration* "}" ;
 func (p *Parser) Block() (*BlockStmt, error) {
+	start := p.Previous()
 	var statements []Stmt
 	for !p.Check(RightBrace) && !p.IsAtEnd() {
 		stmt, err := p.Declaration()
@@ -337,29 +578,31 @@ func (p *Parser) Block() (*BlockStmt, error) {
 	if !p.Match(RightBrace) {
 		return nil, p.Error(p.Peek(), "expect '}' after block")
 	}
-	return &BlockStmt{Statements: statements}, nil
+	return &BlockStmt{Start: start, Statements: statements}, nil
 }
 
 // BreakStatement -> "break" ";" ;
 func (p *Parser) BreakStatement() (Stmt, error) {
+	keyword := p.Previous()
 	if p.LoopDepth == 0 {
 		return nil, p.Error(p.Previous(), "unexpected 'break' outside a loop")
 	}
 	if !p.Match(Semicolon) {
 		return nil, p.Error(p.Peek(), "expect ';' after 'break'")
 	}
-	return &BreakStmt{}, nil
+	return &BreakStmt{Keyword: keyword}, nil
 }
 
 // ContinueStatement -> "continue" ";" ;
 func (p *Parser) ContinueStatement() (Stmt, error) {
+	keyword := p.Previous()
 	if p.LoopDepth == 0 {
 		return nil, p.Error(p.Previous(), "unexpected 'continue' outside a loop")
 	}
 	if !p.Match(Semicolon) {
 		return nil, p.Error(p.Peek(), "expect ';' after 'continue'")
 	}
-	return &ContinueStmt{}, nil
+	return &ContinueStmt{Keyword: keyword}, nil
 }
 
 // Expression -> Assignment ;
@@ -367,22 +610,88 @@ func (p *Parser) Expression() (Expr, error) {
 	return p.Assignment()
 }
 
-// Assignment -> IDENTIFIER "=" Assignment | Lambda ;
+// Assignment -> IDENTIFIER ( "=" | "+=" | "-=" | "*=" | "/=" | "%=" ) Assignment
+//
+//	| Call "[" Expression "]" ( "=" | "+=" | "-=" | "*=" | "/=" | "%=" ) Assignment
+//	| Conditional ;
 func (p *Parser) Assignment() (Expr, error) {
-	expr, err := p.Logical()
+	expr, err := p.Conditional()
 	if err != nil {
 		return nil, err
 	}
-	if p.Match(Equal) {
-		equals := p.Previous()
+	if p.Match(Equal, PlusEqual, MinusEqual, StarEqual, SlashEqual, PercentEqual) {
+		operator := p.Previous()
 		value, err := p.Assignment()
 		if err != nil {
 			return nil, err
 		}
-		if varExpr, ok := expr.(*VariableExpr); ok {
+		if indexExpr, ok := expr.(*IndexExpr); ok {
+			return &SetIndexExpr{
+				Object:   indexExpr.Object,
+				Bracket:  indexExpr.Bracket,
+				Index:    indexExpr.Index,
+				Operator: operator,
+				Value:    value,
+			}, nil
+		}
+		varExpr, ok := expr.(*VariableExpr)
+		if !ok {
+			return nil, p.Error(operator, "invalid assignment target")
+		}
+		if operator.Type == Equal {
 			return &AssignExpr{Name: varExpr.Name, Value: value}, nil
 		}
-		return nil, p.Error(equals, "invalid assignment target")
+		return &CompoundAssignExpr{Name: varExpr.Name, Operator: operator, Value: value}, nil
+	}
+	return expr, nil
+}
+
+// Conditional -> Coalesce ( "?" Expression ":" Conditional )? ;
+func (p *Parser) Conditional() (zero Expr, _ error) {
+	expr, err := p.Coalesce()
+	if err != nil {
+		return zero, err
+	}
+	if p.Match(Question) {
+		question := p.Previous()
+		then, err := p.Expression()
+		if err != nil {
+			return zero, err
+		}
+		if !p.Match(Colon) {
+			return zero, p.Error(p.Peek(), "expect ':' after then branch of conditional expression")
+		}
+		otherwise, err := p.Conditional()
+		if err != nil {
+			return zero, err
+		}
+		expr = &ConditionalExpr{
+			Condition: expr,
+			Question:  question,
+			Then:      then,
+			Else:      otherwise,
+		}
+	}
+	return expr, nil
+}
+
+// Coalesce -> Logical ( "??" Logical )* ;
+func (p *Parser) Coalesce() (zero Expr, _ error) {
+	expr, err := p.Logical()
+	if err != nil {
+		return zero, err
+	}
+	for p.Match(QuestionQuestion) {
+		operator := p.Previous()
+		right, err := p.Logical()
+		if err != nil {
+			return zero, err
+		}
+		expr = &LogicalExpr{
+			Left:     expr,
+			Operator: operator,
+			Right:    right,
+		}
 	}
 	return expr, nil
 }
@@ -429,13 +738,97 @@ func (p *Parser) Equality() (zero Expr, _ error) {
 	return expr, nil
 }
 
-// Comparison -> Term ( ( ">" | ">=" | "<" | "<=" ) Term )* ;
+// Comparison -> BitwiseOr ( ( ">" | ">=" | "<" | "<=" ) BitwiseOr )* ;
 func (p *Parser) Comparison() (zero Expr, _ error) {
-	expr, err := p.Term()
+	expr, err := p.BitwiseOr()
 	if err != nil {
 		return zero, err
 	}
 	for p.Match(Greater, GreaterEqual, Less, LessEqual) {
+		operator := p.Previous()
+		right, err := p.BitwiseOr()
+		if err != nil {
+			return zero, err
+		}
+		expr = &BinaryExpr{
+			Left:     expr,
+			Operator: operator,
+			Right:    right,
+		}
+	}
+	return expr, nil
+}
+
+// BitwiseOr -> BitwiseXor ( "|" BitwiseXor )* ;
+func (p *Parser) BitwiseOr() (zero Expr, _ error) {
+	expr, err := p.BitwiseXor()
+	if err != nil {
+		return zero, err
+	}
+	for p.Match(Pipe) {
+		operator := p.Previous()
+		right, err := p.BitwiseXor()
+		if err != nil {
+			return zero, err
+		}
+		expr = &BinaryExpr{
+			Left:     expr,
+			Operator: operator,
+			Right:    right,
+		}
+	}
+	return expr, nil
+}
+
+// BitwiseXor -> BitwiseAnd ( "^" BitwiseAnd )* ;
+func (p *Parser) BitwiseXor() (zero Expr, _ error) {
+	expr, err := p.BitwiseAnd()
+	if err != nil {
+		return zero, err
+	}
+	for p.Match(Caret) {
+		operator := p.Previous()
+		right, err := p.BitwiseAnd()
+		if err != nil {
+			return zero, err
+		}
+		expr = &BinaryExpr{
+			Left:     expr,
+			Operator: operator,
+			Right:    right,
+		}
+	}
+	return expr, nil
+}
+
+// BitwiseAnd -> Shift ( "&" Shift )* ;
+func (p *Parser) BitwiseAnd() (zero Expr, _ error) {
+	expr, err := p.Shift()
+	if err != nil {
+		return zero, err
+	}
+	for p.Match(Ampersand) {
+		operator := p.Previous()
+		right, err := p.Shift()
+		if err != nil {
+			return zero, err
+		}
+		expr = &BinaryExpr{
+			Left:     expr,
+			Operator: operator,
+			Right:    right,
+		}
+	}
+	return expr, nil
+}
+
+// Shift -> Term ( ( "<<" | ">>" ) Term )* ;
+func (p *Parser) Shift() (zero Expr, _ error) {
+	expr, err := p.Term()
+	if err != nil {
+		return zero, err
+	}
+	for p.Match(LessLess, GreaterGreater) {
 		operator := p.Previous()
 		right, err := p.Term()
 		if err != nil {
@@ -471,13 +864,13 @@ func (p *Parser) Term() (zero Expr, _ error) {
 	return expr, nil
 }
 
-// Factor -> Unary ( ( "/" | "*" ) Unary )* ;
+// Factor -> Unary ( ( "/" | "*" | "%" ) Unary )* ;
 func (p *Parser) Factor() (zero Expr, _ error) {
 	expr, err := p.Unary()
 	if err != nil {
 		return zero, err
 	}
-	for p.Match(Slash, Star) {
+	for p.Match(Slash, Star, Percent) {
 		operator := p.Previous()
 		right, err := p.Unary()
 		if err != nil {
@@ -492,9 +885,19 @@ func (p *Parser) Factor() (zero Expr, _ error) {
 	return expr, nil
 }
 
-// Unary -> ( "!" | "-" ) Unary | Call ;
+// Unary -> ( "!" | "-" | "~" ) Unary | "await" Unary
+//
+//	| ( "++" | "--" ) ( IDENTIFIER | Call "[" Expression "]" ) | Power ;
 func (p *Parser) Unary() (zero Expr, _ error) {
-	if p.Match(Bang, Minus) {
+	if p.Match(PlusPlus, MinusMinus) {
+		operator := p.Previous()
+		target, err := p.Call()
+		if err != nil {
+			return zero, err
+		}
+		return p.increment(target, operator, true)
+	}
+	if p.Match(Bang, Minus, Tilde) {
 		operator := p.Previous()
 		right, err := p.Unary()
 		if err != nil {
@@ -505,19 +908,102 @@ func (p *Parser) Unary() (zero Expr, _ error) {
 			Right:    right,
 		}, nil
 	}
-	return p.Call()
+	if p.Match(Await) {
+		keyword := p.Previous()
+		value, err := p.Unary()
+		if err != nil {
+			return zero, err
+		}
+		return &AwaitExpr{Keyword: keyword, Value: value}, nil
+	}
+	return p.Power()
+}
+
+// Power binds tighter than the unary operators on its left, so -2 ** 2 is
+// -(2 ** 2), and is right-associative, so 2 ** 3 ** 2 is 2 ** (3 ** 2).
+//
+// Power -> Postfix ( "**" Unary )? ;
+func (p *Parser) Power() (zero Expr, _ error) {
+	expr, err := p.Postfix()
+	if err != nil {
+		return zero, err
+	}
+	if p.Match(StarStar) {
+		operator := p.Previous()
+		right, err := p.Unary()
+		if err != nil {
+			return zero, err
+		}
+		expr = &BinaryExpr{
+			Left:     expr,
+			Operator: operator,
+			Right:    right,
+		}
+	}
+	return expr, nil
+}
+
+// Postfix -> ( IDENTIFIER | Call "[" Expression "]" ) ( "++" | "--" ) | Call ;
+func (p *Parser) Postfix() (zero Expr, _ error) {
+	expr, err := p.Call()
+	if err != nil {
+		return zero, err
+	}
+	if p.Match(PlusPlus, MinusMinus) {
+		return p.increment(expr, p.Previous(), false)
+	}
+	return expr, nil
+}
+
+// increment applies a ++ or -- to a variable or a list element.
+func (p *Parser) increment(target Expr, operator Token, prefix bool) (Expr, error) {
+	switch target := target.(type) {
+	case *VariableExpr:
+		return &IncrementExpr{Name: target.Name, Operator: operator, Prefix: prefix}, nil
+	case *IndexExpr:
+		return &SetIndexExpr{
+			Object:   target.Object,
+			Bracket:  target.Bracket,
+			Index:    target.Index,
+			Operator: operator,
+			Prefix:   prefix,
+		}, nil
+	}
+	return nil, p.Error(operator, "invalid increment target")
 }
 
-// Call -> Primary ( "(" Arguments? ")" )* ;
+// Call -> Primary ( "(" Arguments? ")" | "[" Expression "]" | ( "." | "?." ) IDENTIFIER )* ;
 func (p *Parser) Call() (zero Expr, _ error) {
 	expr, err := p.Primary()
 	if err != nil {
 		return zero, err
 	}
-	for p.Match(LeftParen) {
+	for p.Check(LeftParen) || p.Check(LeftBracket) || p.Check(Dot) || p.Check(QuestionDot) {
+		if p.Match(Dot, QuestionDot) {
+			optional := p.Previous().Type == QuestionDot
+			if !p.Match(Identifier) {
+				return zero, p.Error(p.Peek(), "expect property name after '"+p.Previous().Lexeme+"'")
+			}
+			expr = &GetExpr{Object: expr, Name: p.Previous(), Optional: optional}
+			continue
+		}
+		if p.Match(LeftBracket) {
+			bracket := p.Previous()
+			index, err := p.Expression()
+			if err != nil {
+				return zero, err
+			}
+			if !p.Match(RightBracket) {
+				return zero, p.Error(p.Peek(), "expect ']' after index")
+			}
+			expr = &IndexExpr{Object: expr, Bracket: bracket, Index: index}
+			continue
+		}
+		p.Advance() // the "("
 		var arguments []Expr
+		var named []NamedArgument
 		if !p.Check(RightParen) {
-			arguments, err = p.Arguments()
+			arguments, named, err = p.Arguments()
 			if err != nil {
 				return zero, err
 			}
@@ -530,35 +1016,59 @@ func (p *Parser) Call() (zero Expr, _ error) {
 			Callee:    expr,
 			Paren:     paren,
 			Arguments: arguments,
+			Named:     named,
 		}
 	}
 	return expr, nil
 }
 
-// Arguments -> Expression ( "," Expression )* ;
-func (p *Parser) Arguments() ([]Expr, error) {
-	var arguments []Expr
+// Arguments -> Argument ( "," Argument )* ;
+//
+// Argument -> "..."? Expression | IDENTIFIER ":" Expression ;
+func (p *Parser) Arguments() (arguments []Expr, named []NamedArgument, _ error) {
 	for {
-		if len(arguments) >= 255 {
-			return nil, p.Error(p.Peek(), "can't have more than 255 arguments")
+		if len(arguments)+len(named) >= 255 {
+			return nil, nil, p.Error(p.Peek(), "can't have more than 255 arguments")
 		}
-		arg, err := p.Expression()
-		if err != nil {
-			return nil, err
+		if p.Check(Identifier) && p.CheckNext(Colon) {
+			name := p.Advance()
+			p.Advance() // the ':'
+			value, err := p.Expression()
+			if err != nil {
+				return nil, nil, err
+			}
+			named = append(named, NamedArgument{Name: name, Value: value})
+		} else if len(named) > 0 {
+			return nil, nil, p.Error(p.Peek(), "expect named argument after a named argument")
+		} else if p.Match(DotDotDot) {
+			dots := p.Previous()
+			value, err := p.Expression()
+			if err != nil {
+				return nil, nil, err
+			}
+			arguments = append(arguments, &SpreadExpr{Dots: dots, Value: value})
+		} else {
+			arg, err := p.Expression()
+			if err != nil {
+				return nil, nil, err
+			}
+			arguments = append(arguments, arg)
 		}
-		arguments = append(arguments, arg)
 		if !p.Match(Comma) {
 			break
 		}
 	}
-	return arguments, nil
+	return arguments, named, nil
 }
 
-// Primary -> Lambda | NUMBER | STRING | "true" | "false" | "nil" | "(" Expression ")" | IDENTIFIER ;
+// Primary -> Lambda | List | NUMBER | STRING | "true" | "false" | "nil" | "(" Expression ")" | IDENTIFIER ;
 func (p *Parser) Primary() (zero Expr, _ error) {
 	if p.Match(Fun) {
 		return p.Lambda()
 	}
+	if p.Match(LeftBracket) {
+		return p.List()
+	}
 	if p.Match(False) {
 		return &LiteralExpr{Value: false}, nil
 	}
@@ -571,6 +1081,9 @@ func (p *Parser) Primary() (zero Expr, _ error) {
 	if p.Match(Number, String) {
 		return &LiteralExpr{Value: p.Previous().Literal}, nil
 	}
+	if p.Match(Interpolation) {
+		return p.Interpolation()
+	}
 	if p.Match(Identifier) {
 		return &VariableExpr{Name: p.Previous()}, nil
 	}
@@ -587,12 +1100,58 @@ func (p *Parser) Primary() (zero Expr, _ error) {
 	return zero, p.Error(p.Peek(), "expect expression")
 }
 
+// Interpolation -> ( INTERPOLATION Expression )+ STRING ;
+func (p *Parser) Interpolation() (Expr, error) {
+	var parts []Expr
+	for {
+		if text := p.Previous().Literal.(string); text != "" {
+			parts = append(parts, &LiteralExpr{Value: text})
+		}
+		expr, err := p.Expression()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, expr)
+		if p.Match(String) {
+			break
+		}
+		if !p.Match(Interpolation) {
+			return nil, p.Error(p.Peek(), "expect '}' after interpolated expression")
+		}
+	}
+	if text := p.Previous().Literal.(string); text != "" {
+		parts = append(parts, &LiteralExpr{Value: text})
+	}
+	return &InterpolationExpr{Parts: parts}, nil
+}
+
+// List -> "[" ( Expression ( "," Expression )* )? "]" ;
+func (p *Parser) List() (Expr, error) {
+	bracket := p.Previous()
+	var elements []Expr
+	for !p.Check(RightBracket) {
+		element, err := p.Expression()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+		if !p.Match(Comma) {
+			break
+		}
+	}
+	if !p.Match(RightBracket) {
+		return nil, p.Error(p.Peek(), "expect ']' after list elements")
+	}
+	return &ListExpr{Bracket: bracket, Elements: elements}, nil
+}
+
 // Lambda -> "fun" "(" Parameters? ")" Block ;
 func (p *Parser) Lambda() (_ Expr, err error) {
+	keyword := p.Previous()
 	if !p.Match(LeftParen) {
 		return nil, p.Error(p.Peek(), "expect '(' after 'fun'")
 	}
-	var parameters []Token
+	var parameters ParamList
 	if !p.Check(RightParen) {
 		parameters, err = p.Parameters()
 		if err != nil {
@@ -605,15 +1164,15 @@ func (p *Parser) Lambda() (_ Expr, err error) {
 	if !p.Match(LeftBrace) {
 		return nil, p.Error(p.Peek(), "expect '{' before function body")
 	}
-	p.CallableDepth++
-	body, err := p.Block()
+	body, generator, err := p.FunctionBody()
 	if err != nil {
 		return nil, err
 	}
-	p.CallableDepth--
 	return &LambdaExpr{
-		Params: parameters,
-		Body:   body.Statements,
+		Keyword:   keyword,
+		ParamList: parameters,
+		Body:      body,
+		Generator: generator,
 	}, nil
 }
 
@@ -641,6 +1200,14 @@ func (p *Parser) IsAtEnd() bool {
 	return p.Peek().Type == EOF
 }
 
+// CheckNext reports whether the token after the current one is of type t.
+func (p *Parser) CheckNext(t TokenType) bool {
+	if p.IsAtEnd() {
+		return false
+	}
+	return p.Tokens[p.Current+1].Type == t
+}
+
 func (p *Parser) Peek() Token {
 	return p.Tokens[p.Current]
 }
@@ -0,0 +1,291 @@
+package glox
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+var (
+	_ ExprVisitor = AstPrinter{}
+	_ StmtVisitor = printerStmt{}
+)
+
+// AstPrinter renders the syntax tree as parenthesized prefix notation,
+// e.g. `(+ 1 (* 2 3))`, which makes precedence and nesting explicit.
+type AstPrinter struct{}
+
+func (p AstPrinter) Print(program Program) string {
+	var lines []string
+	for _, stmt := range program {
+		lines = append(lines, p.stmt(stmt))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (p AstPrinter) stmt(stmt Stmt) string {
+	var out strings.Builder
+	_ = stmt.Accept(printerStmt{p, &out})
+	return out.String()
+}
+
+func (p AstPrinter) expr(expr Expr) string {
+	s, _ := expr.Accept(p)
+	return s.(string)
+}
+
+func (p AstPrinter) parenthesize(name string, parts ...string) string {
+	return "(" + strings.Join(append([]string{name}, parts...), " ") + ")"
+}
+
+func (p AstPrinter) block(name string, params ParamList, body []Stmt) string {
+	var names []string
+	for i, param := range params.Params {
+		if params.Defaults[i] != nil {
+			names = append(names, p.parenthesize("=", param.Lexeme, p.expr(params.Defaults[i])))
+			continue
+		}
+		names = append(names, param.Lexeme)
+	}
+	if params.Rest != nil {
+		names = append(names, "..."+params.Rest.Lexeme)
+	}
+	parts := []string{"(" + strings.Join(names, " ") + ")"}
+	for _, stmt := range body {
+		parts = append(parts, p.stmt(stmt))
+	}
+	return p.parenthesize(name, parts...)
+}
+
+func (p AstPrinter) VisitBinaryExpr(expr *BinaryExpr) (any, error) {
+	return p.parenthesize(expr.Operator.Lexeme, p.expr(expr.Left), p.expr(expr.Right)), nil
+}
+
+func (p AstPrinter) VisitGroupingExpr(expr *GroupingExpr) (any, error) {
+	return p.parenthesize("group", p.expr(expr.Expression)), nil
+}
+
+func (p AstPrinter) VisitLiteralExpr(expr *LiteralExpr) (any, error) {
+	return p.literal(expr.Value), nil
+}
+
+func (p AstPrinter) literal(value any) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	return stringify(value)
+}
+
+func (p AstPrinter) VisitUnaryExpr(expr *UnaryExpr) (any, error) {
+	return p.parenthesize(expr.Operator.Lexeme, p.expr(expr.Right)), nil
+}
+
+func (p AstPrinter) VisitVariableExpr(expr *VariableExpr) (any, error) {
+	return expr.Name.Lexeme, nil
+}
+
+func (p AstPrinter) VisitAssignExpr(expr *AssignExpr) (any, error) {
+	return p.parenthesize("=", expr.Name.Lexeme, p.expr(expr.Value)), nil
+}
+
+func (p AstPrinter) VisitCompoundAssignExpr(expr *CompoundAssignExpr) (any, error) {
+	return p.parenthesize(expr.Operator.Lexeme, expr.Name.Lexeme, p.expr(expr.Value)), nil
+}
+
+func (p AstPrinter) VisitIncrementExpr(expr *IncrementExpr) (any, error) {
+	if expr.Prefix {
+		return p.parenthesize(expr.Operator.Lexeme, expr.Name.Lexeme), nil
+	}
+	return p.parenthesize("postfix"+expr.Operator.Lexeme, expr.Name.Lexeme), nil
+}
+
+func (p AstPrinter) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	return p.parenthesize("?:", p.expr(expr.Condition), p.expr(expr.Then), p.expr(expr.Else)), nil
+}
+
+func (p AstPrinter) VisitLogicalExpr(expr *LogicalExpr) (any, error) {
+	return p.parenthesize(expr.Operator.Lexeme, p.expr(expr.Left), p.expr(expr.Right)), nil
+}
+
+func (p AstPrinter) VisitCallExpr(expr *CallExpr) (any, error) {
+	parts := []string{p.expr(expr.Callee)}
+	for _, arg := range expr.Arguments {
+		parts = append(parts, p.expr(arg))
+	}
+	for _, arg := range expr.Named {
+		parts = append(parts, p.parenthesize(arg.Name.Lexeme+":", p.expr(arg.Value)))
+	}
+	return p.parenthesize("call", parts...), nil
+}
+
+func (p AstPrinter) VisitSpreadExpr(expr *SpreadExpr) (any, error) {
+	return p.parenthesize("...", p.expr(expr.Value)), nil
+}
+
+func (p AstPrinter) VisitAwaitExpr(expr *AwaitExpr) (any, error) {
+	return p.parenthesize("await", p.expr(expr.Value)), nil
+}
+
+func (p AstPrinter) VisitLambdaExpr(expr *LambdaExpr) (any, error) {
+	return p.block("fun", expr.ParamList, expr.Body), nil
+}
+
+func (p AstPrinter) VisitListExpr(expr *ListExpr) (any, error) {
+	var elements []string
+	for _, element := range expr.Elements {
+		elements = append(elements, p.expr(element))
+	}
+	return p.parenthesize("list", elements...), nil
+}
+
+func (p AstPrinter) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	return p.parenthesize("[]", p.expr(expr.Object), p.expr(expr.Index)), nil
+}
+
+func (p AstPrinter) VisitSetIndexExpr(expr *SetIndexExpr) (any, error) {
+	name := "[]" + expr.Operator.Lexeme
+	if expr.Value == nil {
+		return p.parenthesize(lo.Ternary(expr.Prefix, name, "postfix"+name), p.expr(expr.Object), p.expr(expr.Index)), nil
+	}
+	return p.parenthesize(name, p.expr(expr.Object), p.expr(expr.Index), p.expr(expr.Value)), nil
+}
+
+func (p AstPrinter) VisitGetExpr(expr *GetExpr) (any, error) {
+	return p.parenthesize(lo.Ternary(expr.Optional, "?.", "."), p.expr(expr.Object), expr.Name.Lexeme), nil
+}
+
+func (p AstPrinter) VisitInterpolationExpr(expr *InterpolationExpr) (any, error) {
+	var parts []string
+	for _, part := range expr.Parts {
+		parts = append(parts, p.expr(part))
+	}
+	return p.parenthesize("interpolate", parts...), nil
+}
+
+// statements can't return their rendering through the StmtVisitor
+// interface, so they're written to a builder instead.
+type printerStmt struct {
+	AstPrinter
+	out *strings.Builder
+}
+
+func (p printerStmt) VisitExpressionStmt(stmt *ExpressionStmt) error {
+	p.out.WriteString(p.parenthesize(";", p.expr(stmt.Expr)))
+	return nil
+}
+
+func (p printerStmt) VisitPrintStmt(stmt *PrintStmt) error {
+	p.out.WriteString(p.parenthesize("print", p.expr(stmt.Expr)))
+	return nil
+}
+
+func (p printerStmt) VisitVarDeclStmt(stmt *VarDeclStmt) error {
+	if stmt.Initializer == nil {
+		p.out.WriteString(p.parenthesize("var", stmt.Name.Lexeme))
+		return nil
+	}
+	keyword := lo.Ternary(stmt.Const, "const", "var")
+	p.out.WriteString(p.parenthesize(keyword, stmt.Name.Lexeme, p.expr(stmt.Initializer)))
+	return nil
+}
+
+func (p printerStmt) VisitBlockStmt(stmt *BlockStmt) error {
+	var parts []string
+	for _, s := range stmt.Statements {
+		parts = append(parts, p.stmt(s))
+	}
+	p.out.WriteString(p.parenthesize("block", parts...))
+	return nil
+}
+
+func (p printerStmt) VisitIfStmt(stmt *IfStmt) error {
+	parts := []string{p.expr(stmt.Condition), p.stmt(stmt.ThenBranch)}
+	if stmt.ElseBranch != nil {
+		parts = append(parts, p.stmt(stmt.ElseBranch))
+	}
+	p.out.WriteString(p.parenthesize("if", parts...))
+	return nil
+}
+
+func (p printerStmt) VisitWhileStmt(stmt *WhileStmt) error {
+	parts := []string{p.expr(stmt.Condition), p.stmt(stmt.Body)}
+	if stmt.Increment != nil {
+		parts = append(parts, p.expr(stmt.Increment))
+	}
+	p.out.WriteString(p.parenthesize("while", parts...))
+	return nil
+}
+
+func (p printerStmt) VisitBreakStmt(*BreakStmt) error {
+	p.out.WriteString("(break)")
+	return nil
+}
+
+func (p printerStmt) VisitContinueStmt(*ContinueStmt) error {
+	p.out.WriteString("(continue)")
+	return nil
+}
+
+func (p printerStmt) VisitFunctionStmt(stmt *FunctionStmt) error {
+	p.out.WriteString(p.block("fun "+stmt.Name.Lexeme, stmt.ParamList, stmt.Body))
+	return nil
+}
+
+func (p printerStmt) VisitReturnStmt(stmt *ReturnStmt) error {
+	if stmt.Value == nil {
+		p.out.WriteString("(return)")
+		return nil
+	}
+	p.out.WriteString(p.parenthesize("return", p.expr(stmt.Value)))
+	return nil
+}
+
+func (p printerStmt) VisitMatchStmt(stmt *MatchStmt) error {
+	parts := []string{p.expr(stmt.Subject)}
+	for _, arm := range stmt.Arms {
+		clause := []string{p.pattern(arm.Pattern)}
+		if arm.Guard != nil {
+			clause = append(clause, p.parenthesize("if", p.expr(arm.Guard)))
+		}
+		parts = append(parts, p.parenthesize("case", append(clause, p.stmt(arm.Body))...))
+	}
+	p.out.WriteString(p.parenthesize("match", parts...))
+	return nil
+}
+
+func (p printerStmt) VisitSpawnStmt(stmt *SpawnStmt) error {
+	p.out.WriteString(p.parenthesize("spawn", p.expr(stmt.Call)))
+	return nil
+}
+
+func (p printerStmt) VisitYieldStmt(stmt *YieldStmt) error {
+	p.out.WriteString(p.parenthesize("yield", p.expr(stmt.Value)))
+	return nil
+}
+
+func (p printerStmt) VisitForInStmt(stmt *ForInStmt) error {
+	p.out.WriteString(p.parenthesize("for-in", stmt.Name.Lexeme, p.expr(stmt.Iterable), p.stmt(stmt.Body)))
+	return nil
+}
+
+func (p AstPrinter) pattern(pattern Pattern) string {
+	switch pattern := pattern.(type) {
+	case *LiteralPattern:
+		return p.literal(pattern.Value)
+	case *RangePattern:
+		return p.literal(pattern.Low) + ".." + p.literal(pattern.High)
+	case *BindingPattern:
+		return pattern.Name.Lexeme
+	case *ListPattern:
+		var elements []string
+		for _, element := range pattern.Elements {
+			elements = append(elements, p.pattern(element))
+		}
+		if pattern.Rest != nil {
+			elements = append(elements, "..."+pattern.Rest.Lexeme)
+		}
+		return "[" + strings.Join(elements, " ") + "]"
+	}
+	return ""
+}
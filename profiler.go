@@ -0,0 +1,335 @@
+package glox
+
+import (
+	"cmp"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultProfileRate is the sampling interval used when none is given.
+const DefaultProfileRate = time.Millisecond
+
+// Profiler attributes execution time and call counts to Lox functions and
+// source lines. A background ticker requests a sample of the Lox call stack
+// at a fixed rate, and the interpreter takes it at the next statement or
+// call, so the stack is only ever touched by the interpreter itself. Like
+// Coverage, its recording methods are no-ops on a nil *Profiler.
+type Profiler struct {
+	File string
+	Rate time.Duration
+
+	pending atomic.Int64 // samples requested by the ticker but not taken yet
+	stack   []Frame
+	samples map[string]*profileSample
+	calls   map[string]int // function -> number of calls
+	start   time.Time
+	elapsed time.Duration
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Frame is a Lox function activation and the line it's currently running.
+type Frame struct {
+	Function string
+	Line     int
+}
+
+type profileSample struct {
+	stack   []Frame // leaf first
+	samples int64
+	calls   int64
+}
+
+func NewProfiler(file string, rate time.Duration) *Profiler {
+	return &Profiler{
+		File:    file,
+		Rate:    cmp.Or(rate, DefaultProfileRate),
+		stack:   []Frame{{Function: filepath.Base(file)}}, // top-level code
+		samples: make(map[string]*profileSample),
+		calls:   make(map[string]int),
+	}
+}
+
+// Start begins sampling in the background until Stop is called.
+func (p *Profiler) Start() {
+	p.start = time.Now()
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.Rate)
+		defer ticker.Stop()
+		last := p.start
+		for {
+			select {
+			case now := <-ticker.C:
+				// the ticker drops ticks when this goroutine isn't scheduled
+				// in time, so count every period that went by.
+				n := now.Sub(last) / p.Rate
+				last = last.Add(n * p.Rate)
+				p.pending.Add(int64(n))
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (p *Profiler) Stop() {
+	close(p.stop)
+	<-p.done
+	p.elapsed = time.Since(p.start)
+	p.sample()
+}
+
+// sample takes the samples requested since the last one.
+func (p *Profiler) sample() {
+	if n := p.pending.Swap(0); n > 0 {
+		p.record().samples += n
+	}
+}
+
+// record returns the sample of the current stack.
+func (p *Profiler) record() *profileSample {
+	var key strings.Builder
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		fmt.Fprintf(&key, "%s:%d;", p.stack[i].Function, p.stack[i].Line)
+	}
+	sample, ok := p.samples[key.String()]
+	if !ok {
+		sample = &profileSample{stack: slices.Clone(p.stack)}
+		slices.Reverse(sample.stack)
+		p.samples[key.String()] = sample
+	}
+	return sample
+}
+
+// Enter pushes a new frame for a call to the given callable.
+func (p *Profiler) Enter(callable Callable) {
+	if p == nil {
+		return
+	}
+	p.sample()
+	frame := callableFrame(callable)
+	p.stack = append(p.stack, frame)
+	p.calls[frame.Function]++
+	p.record().calls++
+}
+
+// TailCall replaces the frame on top of the stack, like a tail call
+// replaces its caller.
+func (p *Profiler) TailCall(callable Callable) {
+	if p == nil || len(p.stack) == 0 {
+		return
+	}
+	p.sample()
+	frame := callableFrame(callable)
+	p.stack[len(p.stack)-1] = frame
+	p.calls[frame.Function]++
+	p.record().calls++
+}
+
+func (p *Profiler) Exit() {
+	if p == nil {
+		return
+	}
+	p.sample()
+	p.stack = p.stack[:len(p.stack)-1]
+}
+
+func (p *Profiler) Statement(stmt Stmt) {
+	if p == nil {
+		return
+	}
+	p.sample()
+	p.stack[len(p.stack)-1].Line = stmt.Line()
+}
+
+func callableFrame(callable Callable) Frame {
+	switch callable := callable.(type) {
+	case *Function:
+		return Frame{Function: callable.stmt.Name.Lexeme, Line: callable.stmt.Name.Line}
+	case *Lambda:
+		line := callable.expr.Keyword.Line
+		return Frame{Function: fmt.Sprintf("lambda:%d", line), Line: line}
+	case *NativeFunction:
+		return Frame{Function: callable.name}
+	case *BoundMethod:
+		return callableFrame(callable.method)
+	default:
+		return Frame{Function: fmt.Sprint(callable)}
+	}
+}
+
+// WriteSummary writes a table of the functions sorted by the time spent in
+// them, including their callees.
+func (p *Profiler) WriteSummary(w io.Writer) error {
+	flat := make(map[string]int64)
+	cum := make(map[string]int64)
+	var total int64
+	for _, sample := range p.samples {
+		total += sample.samples
+		if len(sample.stack) > 0 {
+			flat[sample.stack[0].Function] += sample.samples
+		}
+		seen := make(map[string]bool) // recursive frames count once
+		for _, frame := range sample.stack {
+			if !seen[frame.Function] {
+				cum[frame.Function] += sample.samples
+				seen[frame.Function] = true
+			}
+		}
+	}
+
+	functions := slices.Collect(maps.Keys(cum))
+	for function := range p.calls {
+		if _, ok := cum[function]; !ok {
+			functions = append(functions, function)
+		}
+	}
+	slices.SortFunc(functions, func(a, b string) int {
+		return cmp.Or(cmp.Compare(cum[b], cum[a]), cmp.Compare(a, b))
+	})
+
+	fmt.Fprintf(w, "%d samples every %s in %s\n", total, p.Rate, p.elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "%10s %10s %10s  %s\n", "flat", "cum", "calls", "function")
+	for _, function := range functions {
+		calls := fmt.Sprint(p.calls[function])
+		if function == p.stack[0].Function {
+			calls = "-"
+		}
+		fmt.Fprintf(w, "%10s %10s %10s  %s\n",
+			time.Duration(flat[function])*p.Rate, time.Duration(cum[function])*p.Rate, calls, function)
+	}
+	return nil
+}
+
+// WriteProfile writes a gzipped pprof profile where every frame is a Lox
+// function, so `go tool pprof` can be used to explore the script. Besides
+// the sampled time it has a "calls" sample type with the call counts.
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	indexes := map[string]int64{"": 0}
+	table := []string{""}
+	str := func(s string) int64 {
+		if index, ok := indexes[s]; ok {
+			return index
+		}
+		indexes[s] = int64(len(table))
+		table = append(table, s)
+		return indexes[s]
+	}
+	valueType := func(typ, unit string) []byte {
+		var b protobuf
+		b.int(1, str(typ))
+		b.int(2, str(unit))
+		return b
+	}
+
+	var profile protobuf
+	profile.bytes(1, valueType("samples", "count"))
+	profile.bytes(1, valueType("wall", "nanoseconds"))
+	profile.bytes(1, valueType("calls", "count"))
+
+	functions := make(map[string]uint64)
+	locations := make(map[Frame]uint64)
+	var functionTable, locationTable []protobuf
+	for _, key := range slices.Sorted(maps.Keys(p.samples)) {
+		sample := p.samples[key]
+		var ids []uint64
+		for _, frame := range sample.stack {
+			if _, ok := functions[frame.Function]; !ok {
+				functions[frame.Function] = uint64(len(functions) + 1)
+				var function protobuf
+				function.uint(1, functions[frame.Function])
+				function.int(2, str(frame.Function))
+				function.int(3, str(frame.Function))
+				function.int(4, str(p.File))
+				functionTable = append(functionTable, function)
+			}
+			if _, ok := locations[frame]; !ok {
+				locations[frame] = uint64(len(locations) + 1)
+				var line, location protobuf
+				line.uint(1, functions[frame.Function])
+				line.int(2, int64(frame.Line))
+				location.uint(1, locations[frame])
+				location.bytes(4, line)
+				locationTable = append(locationTable, location)
+			}
+			ids = append(ids, locations[frame])
+		}
+		var s protobuf
+		s.packedUints(1, ids)
+		s.packedInts(2, []int64{sample.samples, sample.samples * p.Rate.Nanoseconds(), sample.calls})
+		profile.bytes(2, s)
+	}
+	for _, location := range locationTable {
+		profile.bytes(4, location)
+	}
+	for _, function := range functionTable {
+		profile.bytes(5, function)
+	}
+	periodType := valueType("wall", "nanoseconds")
+	for _, s := range table {
+		profile.bytes(6, []byte(s))
+	}
+	profile.int(9, p.start.UnixNano())
+	profile.int(10, p.elapsed.Nanoseconds())
+	profile.bytes(11, periodType)
+	profile.int(12, p.Rate.Nanoseconds())
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(profile); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// protobuf is a minimal encoder for the messages of the pprof format
+// (https://github.com/google/pprof/blob/main/proto/profile.proto).
+type protobuf []byte
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		*b = append(*b, byte(x)|0x80)
+		x >>= 7
+	}
+	*b = append(*b, byte(x))
+}
+
+func (b *protobuf) uint(field int, x uint64) {
+	b.varint(uint64(field) << 3) // wire type 0: varint
+	b.varint(x)
+}
+
+func (b *protobuf) int(field int, x int64) {
+	b.uint(field, uint64(x))
+}
+
+func (b *protobuf) bytes(field int, data []byte) {
+	b.varint(uint64(field)<<3 | 2) // wire type 2: length-delimited
+	b.varint(uint64(len(data)))
+	*b = append(*b, data...)
+}
+
+func (b *protobuf) packedUints(field int, xs []uint64) {
+	var packed protobuf
+	for _, x := range xs {
+		packed.varint(x)
+	}
+	b.bytes(field, packed)
+}
+
+func (b *protobuf) packedInts(field int, xs []int64) {
+	var packed protobuf
+	for _, x := range xs {
+		packed.varint(uint64(x))
+	}
+	b.bytes(field, packed)
+}
@@ -0,0 +1,155 @@
+package glox_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tangzero/glox"
+)
+
+func TestProfileDecodes(t *testing.T) {
+	profiler, err := glox.ProfileFile("testdata/profile/fib.lox", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var encoded bytes.Buffer
+	if err := profiler.WriteProfile(&encoded); err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(&encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the field numbers are the ones of profile.proto in
+	// https://github.com/google/pprof/blob/main/proto/profile.proto
+	p := decode(t, data)
+	var table []string
+	for _, s := range p.bytes[6] {
+		table = append(table, string(s))
+	}
+	str := func(m message, field int) string {
+		return table[m.varint(field)]
+	}
+
+	var types []string
+	for _, sampleType := range p.messages(t, 1) {
+		types = append(types, str(sampleType, 1)+"/"+str(sampleType, 2))
+	}
+	if expected := []string{"samples/count", "wall/nanoseconds", "calls/count"}; !slices.Equal(types, expected) {
+		t.Errorf("expected sample types %v, got %v", expected, types)
+	}
+	periodType := p.messages(t, 11)[0]
+	if period := p.varint(12); period != uint64(time.Millisecond.Nanoseconds()) || str(periodType, 1) != "wall" {
+		t.Errorf("expected a period of 1ms of wall time, got %d %s", period, str(periodType, 1))
+	}
+
+	functions := make(map[uint64]message)
+	for _, function := range p.messages(t, 5) {
+		functions[function.varint(1)] = function
+	}
+	locations := make(map[uint64]message) // location id -> function
+	for _, location := range p.messages(t, 4) {
+		line := location.messages(t, 4)[0]
+		locations[location.varint(1)] = functions[line.varint(1)]
+	}
+
+	// the calls are exact, unlike the samples
+	calls := make(map[string]uint64)
+	for _, sample := range p.messages(t, 2) {
+		ids := packed(t, sample.bytes[1][0])
+		values := packed(t, sample.bytes[2][0])
+		leaf := locations[ids[0]]
+		calls[str(leaf, 2)] += values[2]
+		if file := str(leaf, 4); file != "testdata/profile/fib.lox" {
+			t.Errorf("expected the frames in the script, got %s", file)
+		}
+		if root := str(locations[ids[len(ids)-1]], 2); root != "fib.lox" {
+			t.Errorf("expected the stacks to start at the top level, got %s", root)
+		}
+	}
+	if calls["fib"] != 1973 || calls["run"] != 1 {
+		t.Errorf("expected 1973 calls of fib and 1 of run, got %d and %d", calls["fib"], calls["run"])
+	}
+}
+
+// message is a decoded protobuf message: its varint and length-delimited
+// fields by field number, which is all the pprof format uses.
+type message struct {
+	varints map[int][]uint64
+	bytes   map[int][][]byte
+}
+
+func decode(t *testing.T, data []byte) message {
+	t.Helper()
+	m := message{make(map[int][]uint64), make(map[int][][]byte)}
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatal("invalid field key")
+		}
+		data = data[n:]
+		field := int(key >> 3)
+		switch key & 7 {
+		case 0:
+			x, n := binary.Uvarint(data)
+			if n <= 0 {
+				t.Fatalf("invalid varint in field %d", field)
+			}
+			m.varints[field] = append(m.varints[field], x)
+			data = data[n:]
+		case 2:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || length > uint64(len(data)-n) {
+				t.Fatalf("invalid length in field %d", field)
+			}
+			m.bytes[field] = append(m.bytes[field], data[n:n+int(length)])
+			data = data[n+int(length):]
+		default:
+			t.Fatalf("unexpected wire type %d in field %d", key&7, field)
+		}
+	}
+	return m
+}
+
+// varint returns the last value of a varint field, 0 if it's missing.
+func (m message) varint(field int) uint64 {
+	if values := m.varints[field]; len(values) > 0 {
+		return values[len(values)-1]
+	}
+	return 0
+}
+
+// messages decodes the embedded messages of a repeated field.
+func (m message) messages(t *testing.T, field int) []message {
+	t.Helper()
+	var messages []message
+	for _, data := range m.bytes[field] {
+		messages = append(messages, decode(t, data))
+	}
+	return messages
+}
+
+// packed decodes a packed repeated varint field.
+func packed(t *testing.T, data []byte) []uint64 {
+	t.Helper()
+	var values []uint64
+	for len(data) > 0 {
+		x, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatal("invalid packed varint")
+		}
+		values = append(values, x)
+		data = data[n:]
+	}
+	return values
+}
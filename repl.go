@@ -0,0 +1,271 @@
+package glox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/peterh/liner"
+)
+
+// HistoryFile is where the REPL keeps its history, relative to the user's
+// home directory.
+const HistoryFile = ".glox_history"
+
+const (
+	prompt             = "> "
+	continuationPrompt = "... "
+)
+
+var replCommands = map[string]string{
+	":help":  "show this help",
+	":env":   "list the global variables",
+	":load":  "run a file in the current session: :load file",
+	":ast":   "print the syntax tree of some code: :ast 1 + 2 * 3",
+	":time":  "run some code and show how long it took: :time fib(20)",
+	":reset": "discard all the state of the session",
+	":quit":  "exit the REPL",
+}
+
+// REPL is an interactive session: an interpreter that lives across inputs
+// plus line editing, history and completion.
+type REPL struct {
+	Interpreter *Interpreter
+	Resolver    *Resolver
+
+	line *liner.State
+}
+
+func NewREPL() *REPL {
+	r := &REPL{line: liner.NewLiner()}
+	r.line.SetCtrlCAborts(true)
+	r.line.SetMultiLineMode(true)
+	r.line.SetWordCompleter(r.complete)
+	r.Reset()
+	if file, err := os.Open(historyPath()); err == nil {
+		r.line.ReadHistory(file)
+		file.Close()
+	}
+	return r
+}
+
+func RunPrompt() error {
+	repl := NewREPL()
+	defer repl.Close()
+	return repl.Run()
+}
+
+// Close restores the terminal and saves the history.
+func (r *REPL) Close() error {
+	defer r.line.Close()
+	file, err := os.Create(historyPath())
+	if err != nil {
+		return err
+	}
+	if _, err := r.line.WriteHistory(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return HistoryFile
+	}
+	return filepath.Join(home, HistoryFile)
+}
+
+// Reset discards every definition made in the session.
+func (r *REPL) Reset() {
+	r.Interpreter = NewInterpreter(DefaultGlobals())
+	// the fibers spawned by an input read the locals while the next one is
+	// resolved
+	r.Interpreter.localsMu = new(sync.RWMutex)
+	r.Resolver = NewResolver(r.Interpreter)
+}
+
+func (r *REPL) Run() error {
+	fmt.Println("Glox REPL. Type :help for help, Ctrl+D to exit.")
+	for {
+		input, err := r.read()
+		if err == io.EOF {
+			fmt.Println()
+			return nil
+		}
+		if err == liner.ErrPromptAborted {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading input: %v", err)
+		}
+		if strings.TrimSpace(input) == "" {
+			continue
+		}
+		r.line.AppendHistory(input)
+
+		if strings.HasPrefix(input, ":") {
+			if quit := r.command(input); quit {
+				return nil
+			}
+			continue
+		}
+		if err := r.Eval(withSemicolon(input)); err != nil {
+			if exit := (*Exit)(nil); errors.As(err, &exit) {
+				return exit
+			}
+			fmt.Println(err)
+		}
+	}
+}
+
+// read reads lines until they form a complete input.
+func (r *REPL) read() (string, error) {
+	input, err := r.line.Prompt(prompt)
+	if err != nil {
+		return "", err
+	}
+	for incomplete(input) {
+		line, err := r.line.Prompt(continuationPrompt)
+		if err != nil {
+			return "", err
+		}
+		input += "\n" + line
+	}
+	return input, nil
+}
+
+// incomplete reports whether the source has unbalanced parentheses,
+// brackets or braces, or an unterminated string, so more lines are needed.
+func incomplete(source string) bool {
+	scanner := NewScanner(source)
+	tokens, err := scanner.ScanTokens()
+	if err != nil {
+		// other errors can't be fixed by the next lines, so they're reported
+		return errors.As(err, new(*unterminatedError))
+	}
+	depth := 0
+	for _, token := range tokens {
+		switch token.Type {
+		case LeftParen, LeftBrace, LeftBracket:
+			depth++
+		case RightParen, RightBrace, RightBracket:
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// Eval runs source in the session. If it's a single expression, its value
+// is printed.
+func (r *REPL) Eval(source string) error {
+	program, err := Parse(source)
+	if err != nil {
+		return err
+	}
+	r.Resolver.Warnings = nil
+	if err := r.Resolver.Resolve(program); err != nil {
+		return err
+	}
+	r.Resolver.PrintWarnings()
+	// if the input is a single expression, wrap it in a print statement.
+	if len(program) == 1 {
+		if expr, ok := program[0].(*ExpressionStmt); ok {
+			program = Program{&PrintStmt{Keyword: expr.Start, Expr: expr.Expr}}
+		}
+	}
+	return r.Interpreter.Interpret(program)
+}
+
+// command runs a meta-command and reports whether the REPL should quit.
+func (r *REPL) command(input string) bool {
+	name, arg, _ := strings.Cut(strings.TrimSpace(input), " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case ":help":
+		for _, command := range slices.Sorted(maps.Keys(replCommands)) {
+			fmt.Printf("%-8s %s\n", command, replCommands[command])
+		}
+	case ":env":
+		globals, ok := r.Interpreter.Globals.(*Environment)
+		if !ok {
+			break
+		}
+		variables := globals.Variables()
+		for _, name := range slices.Sorted(maps.Keys(variables)) {
+			fmt.Printf("%s = %s\n", name, stringify(variables[name]))
+		}
+	case ":load":
+		source, err := os.ReadFile(arg)
+		if err != nil {
+			fmt.Printf("could not read file: %v\n", err)
+		} else if err := r.Eval(string(source)); err != nil {
+			fmt.Println(err)
+		}
+	case ":ast":
+		program, err := Parse(withSemicolon(arg))
+		if err != nil {
+			fmt.Println(err)
+		} else {
+			fmt.Println(AstPrinter{}.Print(program))
+		}
+	case ":time":
+		start := time.Now()
+		err := r.Eval(withSemicolon(arg))
+		elapsed := time.Since(start)
+		if err != nil {
+			fmt.Println(err)
+		}
+		fmt.Printf("took %s\n", elapsed)
+	case ":reset":
+		r.Reset()
+	case ":quit", ":q":
+		return true
+	default:
+		fmt.Printf("unknown command %s, type :help for help\n", name)
+	}
+	return false
+}
+
+// withSemicolon lets the expressions typed in the REPL omit the final ';'.
+func withSemicolon(source string) string {
+	source = strings.TrimSpace(source)
+	if strings.HasSuffix(source, ";") || strings.HasSuffix(source, "}") {
+		return source
+	}
+	return source + ";"
+}
+
+// complete completes the word under the cursor with keywords, globals or
+// meta-commands.
+func (r *REPL) complete(line string, pos int) (head string, completions []string, tail string) {
+	start := pos
+	for start > 0 && (IsAlphaNumeric(line[start-1]) || line[start-1] == ':') {
+		start--
+	}
+	head, word, tail := line[:start], line[start:pos], line[pos:]
+
+	var candidates []string
+	if strings.HasPrefix(word, ":") {
+		candidates = slices.Collect(maps.Keys(replCommands))
+	} else {
+		candidates = slices.Collect(maps.Keys(Keywords))
+		if globals, ok := r.Interpreter.Globals.(*Environment); ok {
+			candidates = slices.AppendSeq(candidates, maps.Keys(globals.Variables()))
+		}
+	}
+	for _, candidate := range slices.Sorted(slices.Values(candidates)) {
+		if strings.HasPrefix(candidate, word) {
+			completions = append(completions, candidate)
+		}
+	}
+	return head, completions, tail
+}
@@ -0,0 +1,67 @@
+package glox
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestIncomplete(t *testing.T) {
+	tests := map[string]bool{
+		"print 1;":            false,
+		"fun f() {":           true,
+		"print (1 +":          true,
+		"var xs = [1,":        true,
+		`print "unterminated`: true,
+		`print """text`:       true,
+		`print "${1 +`:        true,
+		"print 1 @":           false, // reported right away
+		"}":                   false,
+	}
+	for source, expected := range tests {
+		if incomplete(source) != expected {
+			t.Errorf("incomplete(%q): expected %v", source, expected)
+		}
+	}
+}
+
+func TestREPLEval(t *testing.T) {
+	repl := &REPL{}
+	repl.Reset()
+	var output strings.Builder
+	repl.Interpreter.Stdout = &output
+	for _, source := range []string{"// only a comment", "", "var a = 1;", "a + 1;"} {
+		if err := repl.Eval(source); err != nil {
+			t.Fatalf("Eval(%q): %v", source, err)
+		}
+	}
+	if output.String() != "2\n" {
+		t.Errorf("expected output %q, got %q", "2\n", output.String())
+	}
+}
+
+// TestREPLFibersKeepRunning resolves new input while a fiber spawned by an
+// earlier one reads its locals, which the race detector checks.
+func TestREPLFibersKeepRunning(t *testing.T) {
+	repl := &REPL{}
+	repl.Reset()
+	var output strings.Builder
+	repl.Interpreter.Stdout = &output
+	inputs := []string{
+		"var done = channel();",
+		"fun count(n) { var total = 0; for (var i = 0; i < n; i++) total += i; done.send(total); }",
+		"spawn count(100000);",
+	}
+	for i := range 50 {
+		inputs = append(inputs, fmt.Sprintf("{ var local%d = %d; local%d += 1; }", i, i, i))
+	}
+	inputs = append(inputs, "done.receive();")
+	for _, source := range inputs {
+		if err := repl.Eval(source); err != nil {
+			t.Fatalf("Eval(%q): %v", source, err)
+		}
+	}
+	if expected := "4999950000\n"; output.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, output.String())
+	}
+}
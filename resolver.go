@@ -1,8 +1,8 @@
 package glox
 
 import (
-	"errors"
 	"fmt"
+	"os"
 )
 
 var _ Visitor = (*Resolver)(nil)
@@ -10,6 +10,8 @@ var _ Visitor = (*Resolver)(nil)
 type Resolver struct {
 	Interpreter *Interpreter
 	Scopes      *Scopes
+	Warnings    []error // lint warnings, like unreachable match arms
+	Generator   bool    // whether the function being resolved is a generator
 }
 
 func NewResolver(interpreter *Interpreter) *Resolver {
@@ -20,7 +22,7 @@ func NewResolver(interpreter *Interpreter) *Resolver {
 }
 
 func (r *Resolver) BeginScope() {
-	r.Scopes.Push(make(map[string]bool))
+	r.Scopes.Push(NewScope())
 }
 
 func (r *Resolver) EndScope() {
@@ -47,10 +49,8 @@ func (r *Resolver) VisitUnaryExpr(expr *UnaryExpr) (any, error) {
 }
 
 func (r *Resolver) VisitVariableExpr(expr *VariableExpr) (any, error) {
-	if !r.Scopes.Empty() {
-		if s := r.Scopes.Peek(); s.Declared(expr.Name.Lexeme) && !s.Defined(expr.Name.Lexeme) {
-			return nil, errors.New("can't read local variable in its own initializer")
-		}
+	if err := r.CheckInitialized(expr.Name); err != nil {
+		return nil, err
 	}
 	return nil, r.ResolveLocal(expr, expr.Name)
 }
@@ -59,9 +59,45 @@ func (r *Resolver) VisitAssignExpr(expr *AssignExpr) (any, error) {
 	if err := r.ResolveExpr(expr.Value); err != nil {
 		return nil, err
 	}
+	if err := r.CheckAssignable(expr.Name); err != nil {
+		return nil, err
+	}
+	return nil, r.ResolveLocal(expr, expr.Name)
+}
+
+func (r *Resolver) VisitCompoundAssignExpr(expr *CompoundAssignExpr) (any, error) {
+	if err := r.CheckInitialized(expr.Name); err != nil {
+		return nil, err
+	}
+	if err := r.CheckAssignable(expr.Name); err != nil {
+		return nil, err
+	}
+	if err := r.ResolveExpr(expr.Value); err != nil {
+		return nil, err
+	}
+	return nil, r.ResolveLocal(expr, expr.Name)
+}
+
+func (r *Resolver) VisitIncrementExpr(expr *IncrementExpr) (any, error) {
+	if err := r.CheckInitialized(expr.Name); err != nil {
+		return nil, err
+	}
+	if err := r.CheckAssignable(expr.Name); err != nil {
+		return nil, err
+	}
 	return nil, r.ResolveLocal(expr, expr.Name)
 }
 
+func (r *Resolver) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	if err := r.ResolveExpr(expr.Condition); err != nil {
+		return nil, err
+	}
+	if err := r.ResolveExpr(expr.Then); err != nil {
+		return nil, err
+	}
+	return nil, r.ResolveExpr(expr.Else)
+}
+
 func (r *Resolver) VisitLogicalExpr(expr *LogicalExpr) (any, error) {
 	if err := r.ResolveExpr(expr.Left); err != nil {
 		return nil, err
@@ -78,14 +114,66 @@ func (r *Resolver) VisitCallExpr(expr *CallExpr) (any, error) {
 			return nil, err
 		}
 	}
+	for _, arg := range expr.Named {
+		if err := r.ResolveExpr(arg.Value); err != nil {
+			return nil, err
+		}
+	}
 	return nil, nil
 }
 
+func (r *Resolver) VisitSpreadExpr(expr *SpreadExpr) (any, error) {
+	return nil, r.ResolveExpr(expr.Value)
+}
+
+func (r *Resolver) VisitAwaitExpr(expr *AwaitExpr) (any, error) {
+	return nil, r.ResolveExpr(expr.Value)
+}
+
 func (r *Resolver) VisitLambdaExpr(expr *LambdaExpr) (any, error) {
-	return nil, r.ResolveFunction(&FunctionStmt{
-		Params: expr.Params,
-		Body:   expr.Body,
-	})
+	return nil, r.ResolveFunction(expr.ParamList, expr.Body, expr.Generator)
+}
+
+func (r *Resolver) VisitListExpr(expr *ListExpr) (any, error) {
+	for _, element := range expr.Elements {
+		if err := r.ResolveExpr(element); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	if err := r.ResolveExpr(expr.Object); err != nil {
+		return nil, err
+	}
+	return nil, r.ResolveExpr(expr.Index)
+}
+
+func (r *Resolver) VisitSetIndexExpr(expr *SetIndexExpr) (any, error) {
+	if err := r.ResolveExpr(expr.Object); err != nil {
+		return nil, err
+	}
+	if err := r.ResolveExpr(expr.Index); err != nil {
+		return nil, err
+	}
+	if expr.Value == nil { // a ++ or --
+		return nil, nil
+	}
+	return nil, r.ResolveExpr(expr.Value)
+}
+
+func (r *Resolver) VisitGetExpr(expr *GetExpr) (any, error) {
+	return nil, r.ResolveExpr(expr.Object)
+}
+
+func (r *Resolver) VisitInterpolationExpr(expr *InterpolationExpr) (any, error) {
+	for _, part := range expr.Parts {
+		if err := r.ResolveExpr(part); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
 }
 
 func (r *Resolver) VisitExpressionStmt(stmt *ExpressionStmt) error {
@@ -97,9 +185,12 @@ func (r *Resolver) VisitPrintStmt(stmt *PrintStmt) error {
 }
 
 func (r *Resolver) VisitVarDeclStmt(stmt *VarDeclStmt) error {
-	if err := r.Declare(stmt.Name.Lexeme); err != nil {
+	if err := r.Declare(stmt.Name); err != nil {
 		return err
 	}
+	if stmt.Const && !r.Scopes.Empty() {
+		r.Scopes.Peek().DeclareConst(stmt.Name.Lexeme)
+	}
 	if stmt.Initializer != nil {
 		if err := r.ResolveExpr(stmt.Initializer); err != nil {
 			return err
@@ -134,7 +225,13 @@ func (r *Resolver) VisitWhileStmt(stmt *WhileStmt) error {
 	if err := r.ResolveExpr(stmt.Condition); err != nil {
 		return err
 	}
-	return r.ResolveStmt(stmt.Body)
+	if err := r.ResolveStmt(stmt.Body); err != nil {
+		return err
+	}
+	if stmt.Increment != nil {
+		return r.ResolveExpr(stmt.Increment)
+	}
+	return nil
 }
 
 func (r *Resolver) VisitBreakStmt(*BreakStmt) error {
@@ -146,15 +243,136 @@ func (r *Resolver) VisitContinueStmt(*ContinueStmt) error {
 }
 
 func (r *Resolver) VisitReturnStmt(stmt *ReturnStmt) (err error) {
+	if stmt.Value == nil {
+		return nil
+	}
+	if r.Generator {
+		return r.Error(stmt.Keyword, "can't return a value from a generator")
+	}
+	return r.ResolveExpr(stmt.Value)
+}
+
+func (r *Resolver) VisitSpawnStmt(stmt *SpawnStmt) error {
+	return r.ResolveExpr(stmt.Call)
+}
+
+func (r *Resolver) VisitYieldStmt(stmt *YieldStmt) error {
 	return r.ResolveExpr(stmt.Value)
 }
 
+func (r *Resolver) VisitForInStmt(stmt *ForInStmt) error {
+	if err := r.ResolveExpr(stmt.Iterable); err != nil {
+		return err
+	}
+	r.BeginScope()
+	defer r.EndScope()
+	if err := r.Declare(stmt.Name); err != nil {
+		return err
+	}
+	r.Define(stmt.Name.Lexeme)
+	return r.ResolveStmt(stmt.Body)
+}
+
 func (r *Resolver) VisitFunctionStmt(stmt *FunctionStmt) error {
-	if err := r.Declare(stmt.Name.Lexeme); err != nil {
+	if err := r.Declare(stmt.Name); err != nil {
 		return err
 	}
 	r.Define(stmt.Name.Lexeme)
-	return r.ResolveFunction(stmt)
+	return r.ResolveFunction(stmt.ParamList, stmt.Body, stmt.Generator)
+}
+
+func (r *Resolver) VisitMatchStmt(stmt *MatchStmt) error {
+	if err := r.ResolveExpr(stmt.Subject); err != nil {
+		return err
+	}
+	for n, arm := range stmt.Arms {
+		if err := r.ResolveArm(arm); err != nil {
+			return err
+		}
+		for _, earlier := range stmt.Arms[:n] {
+			if earlier.Guard == nil && covers(earlier.Pattern, arm.Pattern) {
+				r.Warn(arm.Case, fmt.Sprintf("unreachable arm, the arm on line %d matches first", earlier.Case.Line))
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveArm resolves a match arm in its own scope, where the names bound
+// by its pattern are defined.
+func (r *Resolver) ResolveArm(arm *MatchArm) error {
+	r.BeginScope()
+	defer r.EndScope()
+	if err := r.DeclarePattern(arm.Pattern); err != nil {
+		return err
+	}
+	if arm.Guard != nil {
+		if err := r.ResolveExpr(arm.Guard); err != nil {
+			return err
+		}
+	}
+	return r.ResolveStmt(arm.Body)
+}
+
+func (r *Resolver) DeclarePattern(pattern Pattern) error {
+	switch p := pattern.(type) {
+	case *BindingPattern:
+		if p.Name.Lexeme == "_" {
+			return nil
+		}
+		if err := r.Declare(p.Name); err != nil {
+			return err
+		}
+		r.Define(p.Name.Lexeme)
+	case *ListPattern:
+		for _, element := range p.Elements {
+			if err := r.DeclarePattern(element); err != nil {
+				return err
+			}
+		}
+		if p.Rest != nil && p.Rest.Lexeme != "_" {
+			if err := r.Declare(*p.Rest); err != nil {
+				return err
+			}
+			r.Define(p.Rest.Lexeme)
+		}
+	}
+	return nil
+}
+
+// covers reports whether every value matched by b is matched by a too.
+func covers(a, b Pattern) bool {
+	switch a := a.(type) {
+	case *BindingPattern:
+		return true
+	case *LiteralPattern:
+		if b, ok := b.(*LiteralPattern); ok {
+			return isEqual(a.Value, b.Value)
+		}
+	case *RangePattern:
+		switch b := b.(type) {
+		case *LiteralPattern:
+			return inRange(b.Value, a.Low, a.High)
+		case *RangePattern:
+			return inRange(b.Low, a.Low, a.High) && inRange(b.High, a.Low, a.High)
+		}
+	case *ListPattern:
+		b, ok := b.(*ListPattern)
+		if !ok || len(b.Elements) < len(a.Elements) {
+			return false
+		}
+		if a.Rest == nil && (b.Rest != nil || len(b.Elements) != len(a.Elements)) {
+			return false
+		}
+		for n, element := range a.Elements {
+			if !covers(element, b.Elements[n]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
 }
 
 func (r *Resolver) Resolve(stmts []Stmt) error {
@@ -175,16 +393,58 @@ func (r *Resolver) ResolveExpr(expr Expr) error {
 	return err
 }
 
-func (r *Resolver) ResolveFunction(stmt *FunctionStmt) error {
+// ResolveFunction resolves the parameters and the body of a function in a
+// new scope. A default value is resolved before its parameter is declared,
+// so it only sees the parameters before it.
+func (r *Resolver) ResolveFunction(params ParamList, body []Stmt, generator bool) error {
+	outer := r.Generator
+	defer func() { r.Generator = outer }()
+	r.Generator = generator
 	r.BeginScope()
 	defer r.EndScope()
-	for _, param := range stmt.Params {
-		if err := r.Declare(param.Lexeme); err != nil {
+	for i, param := range params.Params {
+		if params.Defaults[i] != nil {
+			if err := r.ResolveExpr(params.Defaults[i]); err != nil {
+				return err
+			}
+		}
+		if err := r.Declare(param); err != nil {
 			return err
 		}
 		r.Define(param.Lexeme)
 	}
-	return r.Resolve(stmt.Body)
+	if params.Rest != nil {
+		if err := r.Declare(*params.Rest); err != nil {
+			return err
+		}
+		r.Define(params.Rest.Lexeme)
+	}
+	return r.Resolve(body)
+}
+
+// CheckInitialized reports an error if name is read in the initializer of
+// the local variable it names.
+func (r *Resolver) CheckInitialized(name Token) error {
+	if !r.Scopes.Empty() {
+		if s := r.Scopes.Peek(); s.Declared(name.Lexeme) && !s.Defined(name.Lexeme) {
+			return r.Error(name, "can't read local variable in its own initializer")
+		}
+	}
+	return nil
+}
+
+// CheckAssignable reports an error if name is a local constant. Global
+// constants are checked when the program runs.
+func (r *Resolver) CheckAssignable(name Token) error {
+	for i := r.Scopes.Len() - 1; i >= 0; i-- {
+		if scope := r.Scopes.At(i); scope.Declared(name.Lexeme) {
+			if scope.Const(name.Lexeme) {
+				return r.Error(name, "can't assign to a constant")
+			}
+			return nil
+		}
+	}
+	return nil
 }
 
 func (r *Resolver) ResolveLocal(expr Expr, name Token) error {
@@ -196,15 +456,15 @@ func (r *Resolver) ResolveLocal(expr Expr, name Token) error {
 	return nil
 }
 
-func (r *Resolver) Declare(name string) error {
+func (r *Resolver) Declare(name Token) error {
 	if r.Scopes.Empty() {
 		return nil
 	}
 	scope := r.Scopes.Peek()
-	if scope.Declared(name) {
-		return fmt.Errorf("variable with this name already declared in this scope: %s", name)
+	if scope.Declared(name.Lexeme) {
+		return r.Error(name, "variable with this name already declared in this scope")
 	}
-	scope.Declare(name)
+	scope.Declare(name.Lexeme)
 	return nil
 }
 
@@ -214,3 +474,19 @@ func (r *Resolver) Define(name string) {
 	}
 	r.Scopes.Peek().Define(name)
 }
+
+func (r *Resolver) Error(token Token, message string) error {
+	return Report(token.Line, " at '"+token.Lexeme+"'", message)
+}
+
+// PrintWarnings writes the lint warnings to stderr.
+func (r *Resolver) PrintWarnings() {
+	for _, warning := range r.Warnings {
+		fmt.Fprintln(os.Stderr, warning)
+	}
+}
+
+// Warn records a lint warning, which doesn't stop the program from running.
+func (r *Resolver) Warn(token Token, message string) {
+	r.Warnings = append(r.Warnings, fmt.Errorf("[line %d] Warning at '%s': %s", token.Line, token.Lexeme, message))
+}
@@ -1,8 +1,12 @@
 package glox
 
 import (
+	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/samber/lo"
 )
@@ -26,6 +30,13 @@ var Keywords = map[string]TokenType{
 	"while":    While,
 	"break":    Break,
 	"continue": Continue,
+	"match":    Match,
+	"case":     Case,
+	"const":    Const,
+	"yield":    Yield,
+	"in":       In,
+	"spawn":    Spawn,
+	"await":    Await,
 }
 
 type Scanner struct {
@@ -34,6 +45,13 @@ type Scanner struct {
 	Start   int
 	Current int
 	Line    int
+
+	interpolations []interpolation // the ${ being scanned, innermost last
+}
+
+type interpolation struct {
+	line  int
+	depth int // of the braces opened inside the interpolated expression
 }
 
 func NewScanner(source string) *Scanner {
@@ -45,12 +63,18 @@ func NewScanner(source string) *Scanner {
 }
 
 func (s *Scanner) ScanTokens() ([]Token, error) {
+	if strings.HasPrefix(s.Source, "#!") {
+		s.AdvanceUntil('\n') // the shebang line of an executable script
+	}
 	for !s.AtEnd() {
 		s.Start = s.Current
 		if err := s.ScanToken(); err != nil {
 			return s.Tokens, err
 		}
 	}
+	if n := len(s.interpolations); n > 0 {
+		return s.Tokens, unterminated(s.interpolations[n-1].line, "unterminated string interpolation")
+	}
 	s.Tokens = append(s.Tokens, Token{
 		Type: EOF,
 		Line: s.Line,
@@ -58,6 +82,16 @@ func (s *Scanner) ScanTokens() ([]Token, error) {
 	return s.Tokens, nil
 }
 
+// unterminatedError is a scan error at the end of the input, like an
+// unterminated string, that more input could fix.
+type unterminatedError struct {
+	error
+}
+
+func unterminated(line int, message string) error {
+	return &unterminatedError{Error(line, message)}
+}
+
 func (s *Scanner) AtEnd() bool {
 	return s.Current >= len(s.Source)
 }
@@ -70,42 +104,126 @@ func (s *Scanner) ScanToken() error {
 	case ')':
 		s.AddToken(RightParen)
 	case '{':
+		if n := len(s.interpolations); n > 0 {
+			s.interpolations[n-1].depth++
+		}
 		s.AddToken(LeftBrace)
 	case '}':
+		if n := len(s.interpolations); n > 0 {
+			if s.interpolations[n-1].depth == 0 {
+				// the end of an interpolated expression
+				s.interpolations = s.interpolations[:n-1]
+				return s.ParseStringPart(s.Current)
+			}
+			s.interpolations[n-1].depth--
+		}
 		s.AddToken(RightBrace)
+	case '[':
+		s.AddToken(LeftBracket)
+	case ']':
+		s.AddToken(RightBracket)
 	case ',':
 		s.AddToken(Comma)
 	case '.':
-		s.AddToken(Dot)
+		switch {
+		case strings.HasPrefix(s.Source[s.Current:], ".."):
+			s.Current += 2
+			s.AddToken(DotDotDot)
+		case s.Match('.'):
+			s.AddToken(DotDot)
+		default:
+			s.AddToken(Dot)
+		}
 	case '-':
-		s.AddToken(Minus)
+		switch {
+		case s.Match('-'):
+			s.AddToken(MinusMinus)
+		case s.Match('='):
+			s.AddToken(MinusEqual)
+		default:
+			s.AddToken(Minus)
+		}
 	case '+':
-		s.AddToken(Plus)
+		switch {
+		case s.Match('+'):
+			s.AddToken(PlusPlus)
+		case s.Match('='):
+			s.AddToken(PlusEqual)
+		default:
+			s.AddToken(Plus)
+		}
 	case ';':
 		s.AddToken(Semicolon)
 	case '*':
-		s.AddToken(Star)
+		switch {
+		case s.Match('*'):
+			s.AddToken(StarStar)
+		case s.Match('='):
+			s.AddToken(StarEqual)
+		default:
+			s.AddToken(Star)
+		}
+	case '%':
+		s.AddToken(lo.Ternary(s.Match('='), PercentEqual, Percent))
+	case '^':
+		s.AddToken(Caret)
+	case '&':
+		s.AddToken(Ampersand)
+	case '|':
+		s.AddToken(Pipe)
+	case '~':
+		s.AddToken(Tilde)
+	case ':':
+		s.AddToken(Colon)
+	case '?':
+		switch {
+		case s.Match('?'):
+			s.AddToken(QuestionQuestion)
+		case s.Peek() == '.' && !IsDigit(s.PeekNext()): // not a ? .5 : 1
+			s.Advance()
+			s.AddToken(QuestionDot)
+		default:
+			s.AddToken(Question)
+		}
 	case '!':
 		s.AddToken(lo.Ternary(s.Match('='), BangEqual, Bang))
 	case '=':
-		s.AddToken(lo.Ternary(s.Match('='), EqualEqual, Equal))
+		switch {
+		case s.Match('='):
+			s.AddToken(EqualEqual)
+		case s.Match('>'):
+			s.AddToken(Arrow)
+		default:
+			s.AddToken(Equal)
+		}
 	case '<':
-		s.AddToken(lo.Ternary(s.Match('='), LessEqual, Less))
+		if s.Match('<') {
+			s.AddToken(LessLess)
+		} else {
+			s.AddToken(lo.Ternary(s.Match('='), LessEqual, Less))
+		}
 	case '>':
-		s.AddToken(lo.Ternary(s.Match('='), GreaterEqual, Greater))
+		if s.Match('>') {
+			s.AddToken(GreaterGreater)
+		} else {
+			s.AddToken(lo.Ternary(s.Match('='), GreaterEqual, Greater))
+		}
 	case '/':
 		if s.Match('/') {
 			s.AdvanceUntil('\n') // a comment goes until the end of the line.
 		} else {
-			s.AddToken(Slash)
+			s.AddToken(lo.Ternary(s.Match('='), SlashEqual, Slash))
 		}
 	case ' ', '\r', '\t':
 		// ignore whitespaces
 	case '\n':
 		s.Line++
 	case '"':
-		return s.ParseString()
+		return s.ParseString(false)
 	default:
+		if c == 'r' && s.Match('"') {
+			return s.ParseString(true)
+		}
 		if IsDigit(c) {
 			return s.ParseNumber()
 		}
@@ -171,37 +289,268 @@ func (s *Scanner) AddTokenLiteral(t TokenType, literal any) {
 	})
 }
 
-func (s *Scanner) ParseString() error {
+// ParseString scans a string literal, whose opening quote was consumed.
+// Raw strings, prefixed with r, keep their backslashes as they are.
+// Triple-quoted strings can span lines and have their common indentation
+// stripped.
+func (s *Scanner) ParseString(raw bool) error {
+	if s.Peek() == '"' && s.PeekNext() == '"' {
+		s.Current += 2
+		return s.ParseTextBlock(raw)
+	}
+	if !raw {
+		return s.ParseStringPart(s.Current)
+	}
+	line, start := s.Line, s.Current
 	s.AdvanceUntil('"')
 	if s.AtEnd() {
-		return Error(s.Line, "unterminated string")
+		return unterminated(line, "unterminated string")
+	}
+	s.Advance() // the closing "
+	s.AddTokenLiteral(String, s.Source[start:s.Current-1])
+	return nil
+}
+
+// ParseStringPart scans a string from start until its closing quote or
+// the next ${. In the latter case it emits an Interpolation token, the
+// expression is scanned as usual, and the rest of the string is scanned
+// once the matching } is found.
+func (s *Scanner) ParseStringPart(start int) error {
+	line := s.Line
+	for !s.AtEnd() && s.Peek() != '"' {
+		if s.Peek() == '$' && s.PeekNext() == '{' {
+			value, err := Unescape(s.Source[start:s.Current], line)
+			if err != nil {
+				return err
+			}
+			s.Current += 2 // the ${
+			s.AddTokenLiteral(Interpolation, value)
+			s.interpolations = append(s.interpolations, interpolation{line: s.Line})
+			return nil
+		}
+		if s.Peek() == '\\' {
+			s.Advance() // an escaped quote doesn't end the string
+		}
+		if s.Advance() == '\n' {
+			s.Line++
+		}
+	}
+	if s.AtEnd() {
+		return unterminated(line, "unterminated string")
 	}
 	s.Advance() // the closing "
-	value := s.Source[s.Start+1 : s.Current-1]
+	value, err := Unescape(s.Source[start:s.Current-1], line)
+	if err != nil {
+		return err
+	}
+	s.AddTokenLiteral(String, value)
+	return nil
+}
+
+func (s *Scanner) ParseTextBlock(raw bool) error {
+	line := s.Line
+	start := s.Current
+	for !s.AtEnd() && !strings.HasPrefix(s.Source[s.Current:], `"""`) {
+		if s.Peek() == '\\' && !raw {
+			s.Advance()
+		}
+		if s.Advance() == '\n' {
+			s.Line++
+		}
+	}
+	if s.AtEnd() {
+		return unterminated(line, "unterminated string")
+	}
+	value := s.Source[start:s.Current]
+	s.Current += 3 // the closing """
+	if strings.HasPrefix(value, "\n") {
+		value = value[1:] // the text starts on the line after the quotes
+		line++
+	}
+	value = Dedent(value)
+	if raw {
+		s.AddTokenLiteral(String, value)
+		return nil
+	}
+	// text blocks aren't interpolated, so there's no ${ to escape
+	value, err := unescape(value, line, false)
+	if err != nil {
+		return err
+	}
 	s.AddTokenLiteral(String, value)
 	return nil
 }
 
+// Dedent removes the indentation common to all the non-blank lines of
+// text. The last line counts even if blank, so the closing quotes of a text
+// block set how much is removed.
+func Dedent(text string) string {
+	lines := strings.Split(text, "\n")
+	indent := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" && i < len(lines)-1 {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if len(line) < indent {
+			lines[i] = strings.TrimLeft(line, " \t")
+		} else {
+			lines[i] = line[indent:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unescape replaces the escape sequences of a string literal that starts
+// at line: \n, \t, \r, \0, \\, \", \$ and \u{hex} for any Unicode code point.
+func Unescape(text string, line int) (string, error) {
+	return unescape(text, line, true)
+}
+
+// unescape is Unescape, where \$ is only an escape in the strings that are
+// interpolated.
+func unescape(text string, line int, interpolated bool) (string, error) {
+	if !strings.Contains(text, "\\") {
+		return text, nil
+	}
+	var value strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == '\n' {
+			line++
+		}
+		if c != '\\' {
+			value.WriteByte(c)
+			continue
+		}
+		if i++; i == len(text) {
+			return "", Error(line, "unterminated escape sequence")
+		}
+		switch text[i] {
+		case 'n':
+			value.WriteByte('\n')
+		case 't':
+			value.WriteByte('\t')
+		case 'r':
+			value.WriteByte('\r')
+		case '0':
+			value.WriteByte(0)
+		case '\\', '"':
+			value.WriteByte(text[i])
+		case '$':
+			if !interpolated {
+				return "", Error(line, "invalid escape sequence '\\$' in a text block, where '$' needs no escape")
+			}
+			value.WriteByte(text[i])
+		case 'u':
+			end := strings.IndexByte(text[i:], '}')
+			if !strings.HasPrefix(text[i:], "u{") || end < 0 {
+				return "", Error(line, "invalid unicode escape, expect '\\u{hex}'")
+			}
+			code, err := strconv.ParseUint(text[i+2:i+end], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", Error(line, fmt.Sprintf("invalid unicode code point '%s'", text[i+2:i+end]))
+			}
+			value.WriteRune(rune(code))
+			i += end
+		default:
+			return "", Error(line, fmt.Sprintf("invalid escape sequence '\\%c'", text[i]))
+		}
+	}
+	return value.String(), nil
+}
+
+// ParseNumber scans an integer, in decimal or with a 0x, 0b or 0o prefix,
+// or a decimal float with a fraction and/or an exponent. Digits can be
+// separated by underscores, like 1_000_000, and integers with an n suffix,
+// like 123n, are big integers.
 func (s *Scanner) ParseNumber() error {
-	for unicode.IsDigit(rune(s.Peek())) {
-		s.Advance()
+	base := 10
+	if s.Source[s.Start] == '0' {
+		switch s.Peek() {
+		case 'x', 'X':
+			base = 16
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		}
+	}
+	start := s.Start
+	if base != 10 {
+		s.Advance() // the base prefix
+		start = s.Current
+		if !IsDigitIn(s.Peek(), base) {
+			return Error(s.Line, fmt.Sprintf("expect digits after '%s'", s.Source[s.Start:s.Current]))
+		}
+	}
+	if err := s.ParseDigits(base); err != nil {
+		return err
+	}
+	float := false
+	if base == 10 && s.Peek() == '.' && IsDigit(s.PeekNext()) {
+		float = true
+		s.Advance() // the "."
+		if err := s.ParseDigits(base); err != nil {
+			return err
+		}
 	}
-	if s.Peek() == '.' && IsDigit(s.PeekNext()) {
-		s.Advance() // consume the "."
-		for unicode.IsDigit(rune(s.Peek())) {
+	if base == 10 && (s.Peek() == 'e' || s.Peek() == 'E') {
+		float = true
+		s.Advance() // the "e"
+		if s.Peek() == '+' || s.Peek() == '-' {
 			s.Advance()
 		}
+		if !IsDigit(s.Peek()) {
+			return Error(s.Line, "expect digits in the exponent")
+		}
+		if err := s.ParseDigits(base); err != nil {
+			return err
+		}
+	}
+	bigInt := !float && s.Match('n')
+	if IsAlphaNumeric(s.Peek()) {
+		return Error(s.Line, fmt.Sprintf("invalid character '%c' in number", s.Peek()))
+	}
+
+	text := strings.ReplaceAll(s.Source[start:s.Current], "_", "")
+	if bigInt {
+		number, _ := new(big.Int).SetString(strings.TrimSuffix(text, "n"), base)
+		s.AddTokenLiteral(Number, number)
+		return nil
+	}
+	if float {
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return Error(s.Line, "number out of range")
+		}
+		s.AddTokenLiteral(Number, number)
+		return nil
 	}
-	number, err := strconv.ParseFloat(s.Source[s.Start:s.Current], 64)
+	number, err := strconv.ParseInt(text, base, 64)
 	if err != nil {
-		// if we reach here, something is really wrong with our parser.
-		// just return an error.
-		return Error(s.Line, "invalid number")
+		return Error(s.Line, "integer out of range, use the n suffix for a big integer")
 	}
 	s.AddTokenLiteral(Number, number)
 	return nil
 }
 
+// ParseDigits scans the digits of a number in the given base, with
+// underscores allowed between them.
+func (s *Scanner) ParseDigits(base int) error {
+	for IsDigitIn(s.Peek(), base) || s.Peek() == '_' {
+		if s.Advance() == '_' && !IsDigitIn(s.Peek(), base) {
+			return Error(s.Line, "'_' must separate digits")
+		}
+	}
+	return nil
+}
+
 func (s *Scanner) ParseIdentifier() error {
 	for IsAlphaNumeric(s.Peek()) {
 		s.Advance()
@@ -219,6 +568,17 @@ func IsDigit(c byte) bool {
 	return unicode.IsDigit(rune(c))
 }
 
+// IsDigitIn reports whether c is a digit in base 2, 8, 10 or 16.
+func IsDigitIn(c byte, base int) bool {
+	switch {
+	case base == 16:
+		return IsDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+	case IsDigit(c):
+		return int(c-'0') < base
+	}
+	return false
+}
+
 func IsAlpha(c byte) bool {
 	return unicode.IsLetter(rune(c)) || c == '_'
 }
@@ -1,29 +1,44 @@
 package glox
 
-type Scope map[string]bool
+// Scope tracks the variables of a block. A variable is declared before its
+// initializer is resolved and defined after.
+type Scope struct {
+	variables map[string]bool
+	constants map[string]bool
+}
 
 func NewScope() Scope {
-	return make(map[string]bool)
+	return Scope{make(map[string]bool), make(map[string]bool)}
 }
 
 func (s Scope) Declare(name string) {
-	s[name] = false
+	s.variables[name] = false
 }
 
 func (s Scope) Define(name string) {
-	s[name] = true
+	s.variables[name] = true
+}
+
+// DeclareConst declares a variable that can't be reassigned.
+func (s Scope) DeclareConst(name string) {
+	s.Declare(name)
+	s.constants[name] = true
 }
 
 func (s Scope) Declared(name string) bool {
-	_, ok := s[name]
+	_, ok := s.variables[name]
 	return ok
 }
 
 func (s Scope) Defined(name string) bool {
-	defined, ok := s[name]
+	defined, ok := s.variables[name]
 	return ok && defined
 }
 
+func (s Scope) Const(name string) bool {
+	return s.constants[name]
+}
+
 type Scopes = Stack[Scope]
 
 type Stack[T any] []T
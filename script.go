@@ -0,0 +1,66 @@
+package glox
+
+import (
+	"fmt"
+	"os"
+)
+
+// Script is a program parsed and resolved once, to be run any number of
+// times. Compile is the only one writing to it, so many interpreters can
+// run the same script at the same time, each on its own goroutine.
+//
+// The guarantee stops at the script: an interpreter and its globals belong
+// to a single run, like the fibers it spawns. Give each run its own
+// globals, and make sure the values shared between runs, through natives
+// for instance, are safe for concurrent use.
+type Script struct {
+	Warnings []error // lint warnings, like unreachable match arms
+
+	program Program
+	locals  map[Expr]int // never written after Compile
+}
+
+// Compile parses and resolves source.
+func Compile(source string) (*Script, error) {
+	program, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	// the resolver only records the depth of the locals in the interpreter
+	resolver := NewResolver(NewInterpreter(nil))
+	if err := resolver.Resolve(program); err != nil {
+		return nil, err
+	}
+	return &Script{
+		Warnings: resolver.Warnings,
+		program:  program,
+		locals:   resolver.Interpreter.locals,
+	}, nil
+}
+
+// Program returns the syntax tree of the script, which must not be modified.
+func (s *Script) Program() Program {
+	return s.program
+}
+
+// NewInterpreter returns an interpreter for a run of the script with
+// globals. It shares the resolution of the script, so resolving other code
+// with it fails, unlike with the interpreter of a REPL.
+func (s *Script) NewInterpreter(globals Env) *Interpreter {
+	interpreter := NewInterpreter(globals)
+	interpreter.locals = s.locals
+	interpreter.compiled = true
+	return interpreter
+}
+
+// Run runs the script with globals on a new interpreter.
+func (s *Script) Run(globals Env) error {
+	return s.NewInterpreter(globals).Interpret(s.program)
+}
+
+// PrintWarnings writes the lint warnings to stderr.
+func (s *Script) PrintWarnings() {
+	for _, warning := range s.Warnings {
+		fmt.Fprintln(os.Stderr, warning)
+	}
+}
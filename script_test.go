@@ -0,0 +1,183 @@
+package glox_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tangzero/glox"
+)
+
+// concurrentScript exercises the parts of the interpreter that keep state:
+// closures, generators, fibers and channels.
+const concurrentScript = `
+fun counter() {
+  var count = 0;
+  return fun() { count += 1; return count; };
+}
+
+fun squares(n) {
+  for (var i = 1; i <= n; i++) yield i * i;
+}
+
+var next = counter();
+var sum = 0;
+for (var square in squares(10)) sum += square * next();
+
+var results = channel();
+fun worker(n) { results.send(n * id); }
+for (var n in [1, 2, 3]) spawn worker(n);
+for (var n = 0; n < 3; n++) sum += results.receive();
+print sum;
+`
+
+func TestScriptRunsConcurrently(t *testing.T) {
+	script, err := glox.Compile(concurrentScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const runs = 16
+	outputs := make([]strings.Builder, runs)
+	errs := make([]error, runs)
+	var wg sync.WaitGroup
+	for run := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			globals := glox.DefaultGlobals()
+			globals.Define("id", int64(run))
+			interpreter := script.NewInterpreter(globals)
+			interpreter.Stdout = &outputs[run]
+			errs[run] = interpreter.Interpret(script.Program())
+		}()
+	}
+	wg.Wait()
+
+	for run := range runs {
+		if errs[run] != nil {
+			t.Errorf("run %d: unexpected error %q", run, errs[run])
+		}
+		// the sum of i³ for i up to 10, plus 6 times the id
+		expected := fmt.Sprintln(3025 + 6*run)
+		if output := outputs[run].String(); output != expected {
+			t.Errorf("run %d: expected output %q, got %q", run, expected, output)
+		}
+	}
+}
+
+func TestScriptRunsAreIndependent(t *testing.T) {
+	script, err := glox.Compile("var n = 0; n += 1; print n;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		var output strings.Builder
+		interpreter := script.NewInterpreter(glox.DefaultGlobals())
+		interpreter.Stdout = &output
+		if err := interpreter.Interpret(script.Program()); err != nil {
+			t.Fatal(err)
+		}
+		if output.String() != "1\n" {
+			t.Errorf("expected output %q, got %q", "1\n", output.String())
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	_, err := glox.Compile("{ var a = a; }")
+	expected := "[line 1] Error at 'a': can't read local variable in its own initializer"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+// sharedScript has fibers update a list and drain a generator they share.
+const sharedScript = `
+fun numbers(n) {
+  for (var i = 0; i < n; i++) yield i;
+}
+
+var gen = numbers(1000);
+var counts = channel();
+var xs = [0, 0, 0, 0];
+fun worker(k) {
+  for (var i = 0; i < 100; i++) {
+    xs[k] += 1;
+    xs[0] = k;
+  }
+  counts.send(drain(gen));
+}
+for (var k in [1, 2, 3]) spawn worker(k);
+var total = 0;
+for (var k = 0; k < 3; k++) total += counts.receive();
+print total;
+print xs[1] + xs[2] + xs[3];
+`
+
+func TestFibersShareListsAndGenerators(t *testing.T) {
+	script, err := glox.Compile(sharedScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	globals := glox.DefaultGlobals()
+	// drain counts the values left in a generator, waiting while another
+	// fiber resumes it
+	globals.Define("drain", glox.NewNativeFunction("drain", 1,
+		func(interpreter *glox.Interpreter, args []any) (any, error) {
+			var n int64
+			for {
+				_, done, err := args[0].(*glox.Generator).Next(interpreter)
+				if err == glox.ErrGeneratorRunning {
+					interpreter.Yield()
+					continue
+				}
+				if err != nil || done {
+					return n, err
+				}
+				n++
+			}
+		},
+	))
+	var output strings.Builder
+	interpreter := script.NewInterpreter(globals)
+	interpreter.Stdout = &output
+	if err := interpreter.Interpret(script.Program()); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "1000\n300\n"; output.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, output.String())
+	}
+}
+
+func TestScriptInterpreterCantResolve(t *testing.T) {
+	script, err := glox.Compile("print 1;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := glox.Parse("{ var a = 1; print a; }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := glox.NewResolver(script.NewInterpreter(glox.DefaultGlobals()))
+	if err := resolver.Resolve(program); err == nil {
+		t.Error("expected an error resolving code on the interpreter of a script")
+	}
+}
+
+func TestLocalsIsACopy(t *testing.T) {
+	script, err := glox.Compile("{ var a = 1; print a; }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	interpreter := script.NewInterpreter(glox.DefaultGlobals())
+	locals := interpreter.Locals()
+	if len(locals) != 1 {
+		t.Fatalf("expected 1 local, got %d", len(locals))
+	}
+	clear(locals)
+	if len(interpreter.Locals()) != 1 {
+		t.Error("clearing the copy of the locals changed the interpreter")
+	}
+}
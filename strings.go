@@ -0,0 +1,247 @@
+package glox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxStringLength bounds the strings the natives build, in bytes, so a
+// huge count is a runtime error instead of exhausting the memory.
+const maxStringLength = 1 << 28
+
+// The string library. Its natives take the string as their first argument,
+// so they double as the string methods: s.upper() is upper(s). Positions
+// and lengths count characters (runes), not bytes.
+var stringLibrary = map[string]Callable{
+	"len": NewNativeFunction("len", 1,
+		func(_ *Interpreter, args []any) (any, error) {
+			switch value := args[0].(type) {
+			case string:
+				return int64(utf8.RuneCountInString(value)), nil
+			case *List:
+				return int64(value.Len()), nil
+			}
+			return nil, fmt.Errorf("len expects a string or a list, got %T", args[0])
+		},
+	),
+	"substr": NewVariadicNativeFunction("substr", 2, 3,
+		func(_ *Interpreter, args []any) (any, error) {
+			s, err := stringArg("substr", args[0])
+			if err != nil {
+				return nil, err
+			}
+			runes := []rune(s)
+			start, err := integerArg("substr", args[1])
+			if err != nil {
+				return nil, err
+			}
+			end := int64(len(runes)) // to the end of the string by default
+			if len(args) == 3 {
+				if end, err = integerArg("substr", args[2]); err != nil {
+					return nil, err
+				}
+			}
+			if start < 0 || end < start || end > int64(len(runes)) {
+				return nil, fmt.Errorf("substr range [%d, %d) out of bounds for length %d", start, end, len(runes))
+			}
+			return string(runes[start:end]), nil
+		},
+	),
+	"upper": stringFunction("upper", strings.ToUpper),
+	"lower": stringFunction("lower", strings.ToLower),
+	"trim":  stringFunction("trim", strings.TrimSpace),
+	"split": NewNativeFunction("split", 2,
+		func(_ *Interpreter, args []any) (any, error) {
+			s, sep, err := stringArgs("split", args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			var elements []any
+			for _, part := range strings.Split(s, sep) {
+				elements = append(elements, part)
+			}
+			return NewList(elements), nil
+		},
+	),
+	"join": NewVariadicNativeFunction("join", 1, 2,
+		func(_ *Interpreter, args []any) (any, error) {
+			list, ok := args[0].(*List)
+			if !ok {
+				return nil, fmt.Errorf("join expects a list, got %T", args[0])
+			}
+			sep := "" // no separator by default
+			if len(args) == 2 {
+				var err error
+				if sep, err = stringArg("join", args[1]); err != nil {
+					return nil, err
+				}
+			}
+			elements := list.Values()
+			parts := make([]string, len(elements))
+			for i, element := range elements {
+				parts[i] = stringify(element)
+			}
+			return strings.Join(parts, sep), nil
+		},
+	),
+	"replace": NewNativeFunction("replace", 3,
+		func(_ *Interpreter, args []any) (any, error) {
+			s, old, err := stringArgs("replace", args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			replacement, err := stringArg("replace", args[2])
+			if err != nil {
+				return nil, err
+			}
+			return strings.ReplaceAll(s, old, replacement), nil
+		},
+	),
+	"startsWith": NewNativeFunction("startsWith", 2,
+		func(_ *Interpreter, args []any) (any, error) {
+			s, prefix, err := stringArgs("startsWith", args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			return strings.HasPrefix(s, prefix), nil
+		},
+	),
+	"endsWith": NewNativeFunction("endsWith", 2,
+		func(_ *Interpreter, args []any) (any, error) {
+			s, suffix, err := stringArgs("endsWith", args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			return strings.HasSuffix(s, suffix), nil
+		},
+	),
+	"indexOf": NewNativeFunction("indexOf", 2,
+		func(_ *Interpreter, args []any) (any, error) {
+			s, sub, err := stringArgs("indexOf", args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			index := strings.Index(s, sub)
+			if index < 0 {
+				return int64(-1), nil
+			}
+			return int64(utf8.RuneCountInString(s[:index])), nil
+		},
+	),
+	"repeat": NewNativeFunction("repeat", 2,
+		func(_ *Interpreter, args []any) (any, error) {
+			s, err := stringArg("repeat", args[0])
+			if err != nil {
+				return nil, err
+			}
+			count, err := integerArg("repeat", args[1])
+			if err != nil {
+				return nil, err
+			}
+			if count < 0 {
+				return nil, fmt.Errorf("repeat expects a non-negative count, got %d", count)
+			}
+			if count > 0 && int64(len(s)) > maxStringLength/count {
+				return nil, fmt.Errorf("repeat would make a string over %d bytes", maxStringLength)
+			}
+			return strings.Repeat(s, int(count)), nil
+		},
+	),
+	"chars": NewNativeFunction("chars", 1,
+		func(_ *Interpreter, args []any) (any, error) {
+			s, err := stringArg("chars", args[0])
+			if err != nil {
+				return nil, err
+			}
+			var elements []any
+			for _, r := range s {
+				elements = append(elements, string(r))
+			}
+			return NewList(elements), nil
+		},
+	),
+	"format": NewVariadicNativeFunction("format", 1, Unlimited,
+		func(_ *Interpreter, args []any) (any, error) {
+			template, err := stringArg("format", args[0])
+			if err != nil {
+				return nil, err
+			}
+			return format(template, args[1:])
+		},
+	),
+}
+
+// listMethods are the natives of the string library that take a list as
+// their first argument.
+var listMethods = []string{"len", "join"}
+
+// DefineStrings installs the string library in env.
+func DefineStrings(env Env) {
+	for name, native := range stringLibrary {
+		env.Define(name, native)
+	}
+}
+
+// format replaces each {} of the template with the next argument and each
+// {n} with the nth one. {{ and }} stand for literal braces.
+func format(template string, args []any) (string, error) {
+	var out strings.Builder
+	next := 0
+	for i := 0; i < len(template); i++ {
+		switch {
+		case strings.HasPrefix(template[i:], "{{"):
+			out.WriteByte('{')
+			i++
+		case strings.HasPrefix(template[i:], "}}"):
+			out.WriteByte('}')
+			i++
+		case template[i] == '{':
+			end := strings.IndexByte(template[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("format template has an unclosed '{'")
+			}
+			index := next
+			if placeholder := template[i+1 : i+end]; placeholder == "" {
+				next++
+			} else if n, err := strconv.Atoi(placeholder); err == nil {
+				index = n
+			} else {
+				return "", fmt.Errorf("format template has an invalid placeholder {%s}", placeholder)
+			}
+			if index < 0 || index >= len(args) {
+				return "", fmt.Errorf("format expects an argument for placeholder %d", index)
+			}
+			out.WriteString(stringify(args[index]))
+			i += end
+		default:
+			out.WriteByte(template[i])
+		}
+	}
+	return out.String(), nil
+}
+
+func stringFunction(name string, fn func(string) string) Callable {
+	return NewNativeFunction(name, 1,
+		func(_ *Interpreter, args []any) (any, error) {
+			s, err := stringArg(name, args[0])
+			if err != nil {
+				return nil, err
+			}
+			return fn(s), nil
+		},
+	)
+}
+
+func stringArgs(native string, a, b any) (string, string, error) {
+	x, err := stringArg(native, a)
+	if err != nil {
+		return "", "", err
+	}
+	y, err := stringArg(native, b)
+	if err != nil {
+		return "", "", err
+	}
+	return x, y, nil
+}
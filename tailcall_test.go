@@ -0,0 +1,60 @@
+package glox_test
+
+import (
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/tangzero/glox"
+)
+
+// TestTailCallsDontGrowTheStack runs recursions far deeper than the stack
+// allows, which only works if the calls in tail position don't nest.
+func TestTailCallsDontGrowTheStack(t *testing.T) {
+	defer debug.SetMaxStack(debug.SetMaxStack(16 << 20))
+
+	tests := map[string]string{
+		"recursion": `
+fun loop(n) {
+  if (n == 0) return "done";
+  return loop(n - 1);
+}
+print loop(200000);
+`,
+		"mutual recursion": `
+fun isEven(n) {
+  if (n == 0) return true;
+  return isOdd(n - 1);
+}
+fun isOdd(n) {
+  if (n == 0) return false;
+  return isEven(n - 1);
+}
+print isEven(200000) and !isOdd(200000) ? "done" : "wrong";
+`,
+		"lambda": `
+var loop = fun(n) {
+  if (n == 0) return "done";
+  return loop(n - 1);
+};
+print loop(200000);
+`,
+	}
+	for name, source := range tests {
+		t.Run(name, func(t *testing.T) {
+			script, err := glox.Compile(source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var output strings.Builder
+			interpreter := script.NewInterpreter(glox.DefaultGlobals())
+			interpreter.Stdout = &output
+			if err := interpreter.Interpret(script.Program()); err != nil {
+				t.Fatal(err)
+			}
+			if output.String() != "done\n" {
+				t.Errorf("expected output %q, got %q", "done\n", output.String())
+			}
+		})
+	}
+}
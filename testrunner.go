@@ -0,0 +1,384 @@
+package glox
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TestFileSuffix is the suffix of the files discovered by the test runner.
+const TestFileSuffix = "_test.lox"
+
+// TestFunctionPrefix is the prefix of the top-level functions run as tests,
+// followed by anything but a lowercase letter, like testParse or test_parse,
+// so a helper like tester isn't one.
+const TestFunctionPrefix = "test"
+
+func isTestName(name string) bool {
+	rest, ok := strings.CutPrefix(name, TestFunctionPrefix)
+	if !ok {
+		return false
+	}
+	next, _ := utf8.DecodeRuneInString(rest)
+	return rest == "" || !unicode.IsLower(next)
+}
+
+type TestResult struct {
+	Name     string
+	Err      error // nil if the test passed
+	Output   string
+	Duration time.Duration
+}
+
+func (r *TestResult) Passed() bool {
+	return r.Err == nil
+}
+
+type TestSuite struct {
+	File     string
+	Err      error  // set when the file could not be loaded at all
+	Output   string // printed by the top-level code
+	Results  []*TestResult
+	Duration time.Duration
+}
+
+func (s *TestSuite) Failures() int {
+	failures := 0
+	for _, result := range s.Results {
+		if !result.Passed() {
+			failures++
+		}
+	}
+	return failures
+}
+
+func (s *TestSuite) Passed() bool {
+	return s.Err == nil && s.Failures() == 0
+}
+
+// DiscoverTests walks the given paths and returns every test file found.
+// Paths that name a file are returned as is.
+func DiscoverTests(paths ...string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(d.Name(), TestFileSuffix) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func RunTests(paths ...string) ([]*TestSuite, error) {
+	files, err := DiscoverTests(paths...)
+	if err != nil {
+		return nil, err
+	}
+	var suites []*TestSuite
+	for _, file := range files {
+		suites = append(suites, RunTestFile(file))
+	}
+	return suites, nil
+}
+
+// RunTestFile runs the top-level code of a file once, then every test
+// function of the file. Each test gets a fresh interpreter, with a copy of
+// the globals the top-level code left, so a test assigning a global can't
+// leak into another. The values of the globals, like lists, are shared.
+func RunTestFile(path string) *TestSuite {
+	suite := &TestSuite{File: path}
+	start := time.Now()
+	defer func() { suite.Duration = time.Since(start) }()
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		suite.Err = fmt.Errorf("could not read file: %v", err)
+		return suite
+	}
+	script, err := Compile(string(bytes))
+	if err != nil {
+		suite.Err = err
+		return suite
+	}
+
+	var output strings.Builder
+	interpreter := script.NewInterpreter(TestGlobals())
+	interpreter.Stdout = &output
+	err = interpreter.Interpret(script.Program())
+	suite.Output = output.String()
+	if err != nil {
+		suite.Err = err
+		return suite
+	}
+	globals := interpreter.Globals.(*Environment)
+	for _, stmt := range script.Program() {
+		if fn, ok := stmt.(*FunctionStmt); ok && isTestName(fn.Name.Lexeme) {
+			suite.Results = append(suite.Results, runTest(script, globals.clone(), fn.Name))
+		}
+	}
+	return suite
+}
+
+func runTest(script *Script, globals *Environment, name Token) *TestResult {
+	result := &TestResult{Name: name.Lexeme}
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+
+	var output bytes.Buffer
+	defer func() { result.Output = output.String() }()
+	defer func() {
+		// a test can't end the whole run, so exit() fails it, even exit(0)
+		if exit := (*Exit)(nil); errors.As(result.Err, &exit) {
+			result.Err = fmt.Errorf("test called exit(%d)", exit.Code)
+		}
+	}()
+
+	interpreter := script.NewInterpreter(globals)
+	interpreter.Stdout = &output
+	test, err := globals.Get(name)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	function, ok := test.(*Function)
+	if !ok {
+		result.Err = Error(name.Line, fmt.Sprintf("test '%s' was redefined as %s", name.Lexeme, stringify(test)))
+		return result
+	}
+	if !function.Arity().Accepts(0) {
+		result.Err = Error(name.Line, "test functions must not take parameters")
+		return result
+	}
+	leave := interpreter.turn.enter()
+	defer leave()
+	value, err := function.Call(interpreter, nil)
+	if generator, ok := value.(*Generator); ok && err == nil {
+		// the test yields, run it to its end
+		for done := false; !done && err == nil; {
+			_, done, err = generator.Next(interpreter)
+		}
+	}
+	result.Err = err
+	return result
+}
+
+// TestGlobals returns the default globals plus the assertion natives.
+func TestGlobals() Env {
+	env := DefaultGlobals()
+	env.Define("assert", NewNativeFunction("assert", 1,
+		func(_ *Interpreter, args []any) (any, error) {
+			if !isTruthy(args[0]) {
+				return nil, fmt.Errorf("assertion failed")
+			}
+			return nil, nil
+		},
+	))
+	env.Define("assertEqual", NewNativeFunction("assertEqual", 2,
+		func(_ *Interpreter, args []any) (any, error) {
+			if !isEqual(args[0], args[1]) {
+				return nil, fmt.Errorf("assertion failed: expected %s, got %s", stringify(args[1]), stringify(args[0]))
+			}
+			return nil, nil
+		},
+	))
+	env.Define("assertThrows", NewNativeFunction("assertThrows", 1,
+		func(i *Interpreter, args []any) (any, error) {
+			callable, ok := args[0].(Callable)
+			if !ok || !callable.Arity().Accepts(0) {
+				return nil, fmt.Errorf("assertThrows expects a function without parameters")
+			}
+			_, err := callable.Call(i, nil)
+			if exit := (*Exit)(nil); errors.As(err, &exit) {
+				return nil, err // exit() isn't an error to assert on
+			}
+			if err == nil {
+				return nil, fmt.Errorf("assertion failed: expected an error")
+			}
+			return nil, nil
+		},
+	))
+	return env
+}
+
+// Supported test report formats.
+const (
+	ReportHuman = "human"
+	ReportJUnit = "junit"
+	ReportTAP   = "tap"
+)
+
+func WriteTestReport(w io.Writer, format string, suites []*TestSuite) error {
+	switch format {
+	case ReportHuman:
+		return writeHumanReport(w, suites)
+	case ReportJUnit:
+		return writeJUnitReport(w, suites)
+	case ReportTAP:
+		return writeTAPReport(w, suites)
+	default:
+		return fmt.Errorf("unknown report format: %s", format)
+	}
+}
+
+func writeHumanReport(w io.Writer, suites []*TestSuite) error {
+	for _, suite := range suites {
+		if suite.Err != nil {
+			fmt.Fprintf(w, "FAIL\t%s\n\t%v\n", suite.File, suite.Err)
+			continue
+		}
+		for _, result := range suite.Results {
+			status := "PASS"
+			if !result.Passed() {
+				status = "FAIL"
+			}
+			fmt.Fprintf(w, "--- %s: %s (%s)\n", status, result.Name, result.Duration)
+			if !result.Passed() {
+				fmt.Fprintf(w, "\t%v\n", result.Err)
+				for line := range strings.Lines(result.Output) {
+					fmt.Fprintf(w, "\t%s", line)
+				}
+			}
+		}
+		status := "ok"
+		if !suite.Passed() {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", status, suite.File, suite.Duration)
+	}
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Error    *junitMessage   `xml:"error,omitempty"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+func writeJUnitReport(w io.Writer, suites []*TestSuite) error {
+	report := junitTestSuites{}
+	for _, suite := range suites {
+		junitSuite := junitTestSuite{
+			Name:     suite.File,
+			Tests:    len(suite.Results),
+			Failures: suite.Failures(),
+			Time:     seconds(suite.Duration),
+		}
+		if suite.Err != nil {
+			junitSuite.Errors = 1
+			junitSuite.Error = &junitMessage{Message: suite.Err.Error()}
+		}
+		for _, result := range suite.Results {
+			testCase := junitTestCase{
+				Name:      result.Name,
+				Classname: suite.File,
+				Time:      seconds(result.Duration),
+				SystemOut: result.Output,
+			}
+			if !result.Passed() {
+				testCase.Failure = &junitMessage{Message: result.Err.Error()}
+			}
+			junitSuite.Cases = append(junitSuite.Cases, testCase)
+		}
+		report.Suites = append(report.Suites, junitSuite)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func writeTAPReport(w io.Writer, suites []*TestSuite) error {
+	total := 0
+	for _, suite := range suites {
+		total += max(len(suite.Results), 1)
+	}
+	fmt.Fprintln(w, "TAP version 13")
+	fmt.Fprintf(w, "1..%d\n", total)
+	n := 0
+	for _, suite := range suites {
+		if suite.Err != nil || len(suite.Results) == 0 {
+			n++
+			if suite.Err != nil {
+				fmt.Fprintf(w, "not ok %d - %s\n", n, suite.File)
+				writeTAPDiagnostic(w, suite.Err.Error())
+			} else {
+				fmt.Fprintf(w, "ok %d - %s # SKIP no tests\n", n, suite.File)
+			}
+			continue
+		}
+		for _, result := range suite.Results {
+			n++
+			status := "ok"
+			if !result.Passed() {
+				status = "not ok"
+			}
+			fmt.Fprintf(w, "%s %d - %s: %s # time=%s\n", status, n, suite.File, result.Name, result.Duration)
+			if !result.Passed() {
+				writeTAPDiagnostic(w, result.Err.Error()+"\n"+result.Output)
+			}
+		}
+	}
+	return nil
+}
+
+func writeTAPDiagnostic(w io.Writer, message string) {
+	for line := range strings.Lines(strings.TrimRight(message, "\n")) {
+		fmt.Fprintf(w, "# %s\n", strings.TrimSuffix(line, "\n"))
+	}
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,131 @@
+package glox_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tangzero/glox"
+)
+
+const sampleTests = "testdata/testrunner/sample_test.lox"
+
+func TestRunTestFile(t *testing.T) {
+	suite := glox.RunTestFile(sampleTests)
+	if suite.Err != nil {
+		t.Fatal(suite.Err)
+	}
+	if suite.Output != "loading\n" {
+		t.Errorf("expected the top-level code to print %q once, got %q", "loading\n", suite.Output)
+	}
+
+	expected := []struct {
+		name   string
+		err    string
+		output string
+	}{
+		{"testPasses", "", ""},
+		{"testFails", "[line 7] Error: assertion failed: expected 3, got 2.", "some output\n"},
+		{"testThrows", "", ""},
+		{"testExits", "test called exit(0)", ""},
+		{"testTopLevelRunsOnce", "", ""},
+		{"test_generator", "[line 37] Error: assertion failed: expected 2, got 1.", ""},
+	}
+	if len(suite.Results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(suite.Results))
+	}
+	for i, result := range suite.Results {
+		if result.Name != expected[i].name {
+			t.Errorf("expected test %q, got %q", expected[i].name, result.Name)
+		}
+		err := ""
+		if result.Err != nil {
+			err = result.Err.Error()
+		}
+		if err != expected[i].err {
+			t.Errorf("%s: expected error %q, got %q", result.Name, expected[i].err, err)
+		}
+		if result.Output != expected[i].output {
+			t.Errorf("%s: expected output %q, got %q", result.Name, expected[i].output, result.Output)
+		}
+	}
+	if suite.Passed() || suite.Failures() != 3 {
+		t.Errorf("expected the suite to fail with 3 failures, got %d", suite.Failures())
+	}
+}
+
+func TestTestReports(t *testing.T) {
+	suite := glox.RunTestFile(sampleTests)
+	// the timings change from run to run
+	suite.Duration = 0
+	for _, result := range suite.Results {
+		result.Duration = 0
+	}
+
+	reports := map[string]string{
+		glox.ReportHuman: `--- PASS: testPasses (0s)
+--- FAIL: testFails (0s)
+	[line 7] Error: assertion failed: expected 3, got 2.
+	some output
+--- PASS: testThrows (0s)
+--- FAIL: testExits (0s)
+	test called exit(0)
+--- PASS: testTopLevelRunsOnce (0s)
+--- FAIL: test_generator (0s)
+	[line 37] Error: assertion failed: expected 2, got 1.
+FAIL	testdata/testrunner/sample_test.lox	0s
+`,
+		glox.ReportTAP: `TAP version 13
+1..6
+ok 1 - testdata/testrunner/sample_test.lox: testPasses # time=0s
+not ok 2 - testdata/testrunner/sample_test.lox: testFails # time=0s
+# [line 7] Error: assertion failed: expected 3, got 2.
+# some output
+ok 3 - testdata/testrunner/sample_test.lox: testThrows # time=0s
+not ok 4 - testdata/testrunner/sample_test.lox: testExits # time=0s
+# test called exit(0)
+ok 5 - testdata/testrunner/sample_test.lox: testTopLevelRunsOnce # time=0s
+not ok 6 - testdata/testrunner/sample_test.lox: test_generator # time=0s
+# [line 37] Error: assertion failed: expected 2, got 1.
+`,
+		glox.ReportJUnit: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testdata/testrunner/sample_test.lox" tests="6" failures="3" errors="0" time="0.000">
+    <testcase name="testPasses" classname="testdata/testrunner/sample_test.lox" time="0.000"></testcase>
+    <testcase name="testFails" classname="testdata/testrunner/sample_test.lox" time="0.000">
+      <failure message="[line 7] Error: assertion failed: expected 3, got 2."></failure>
+      <system-out>some output&#xA;</system-out>
+    </testcase>
+    <testcase name="testThrows" classname="testdata/testrunner/sample_test.lox" time="0.000"></testcase>
+    <testcase name="testExits" classname="testdata/testrunner/sample_test.lox" time="0.000">
+      <failure message="test called exit(0)"></failure>
+    </testcase>
+    <testcase name="testTopLevelRunsOnce" classname="testdata/testrunner/sample_test.lox" time="0.000"></testcase>
+    <testcase name="test_generator" classname="testdata/testrunner/sample_test.lox" time="0.000">
+      <failure message="[line 37] Error: assertion failed: expected 2, got 1."></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+	}
+	for format, expected := range reports {
+		t.Run(format, func(t *testing.T) {
+			var report strings.Builder
+			if err := glox.WriteTestReport(&report, format, []*glox.TestSuite{suite}); err != nil {
+				t.Fatal(err)
+			}
+			if report.String() != expected {
+				t.Errorf("expected report:\n%s\ngot:\n%s", expected, report.String())
+			}
+		})
+	}
+}
+
+func TestDiscoverTests(t *testing.T) {
+	files, err := glox.DiscoverTests("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0] != sampleTests {
+		t.Errorf("expected [%s], got %v", sampleTests, files)
+	}
+}
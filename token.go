@@ -10,6 +10,8 @@ const (
 	RightParen
 	LeftBrace
 	RightBrace
+	LeftBracket
+	RightBracket
 	Comma
 	Dot
 	Minus
@@ -17,6 +19,13 @@ const (
 	Semicolon
 	Slash
 	Star
+	Percent
+	Caret
+	Ampersand
+	Pipe
+	Tilde
+	Colon
+	Question
 
 	// One or two character tokens
 	Bang
@@ -27,11 +36,27 @@ const (
 	GreaterEqual
 	Less
 	LessEqual
+	LessLess
+	GreaterGreater
+	StarStar
+	PlusEqual
+	MinusEqual
+	StarEqual
+	SlashEqual
+	PercentEqual
+	PlusPlus
+	MinusMinus
+	QuestionQuestion
+	QuestionDot
+	DotDot
+	DotDotDot
+	Arrow
 
 	// Literals
 	Identifier
 	String
 	Number
+	Interpolation // the part of a string literal before a ${
 
 	// Keywords
 	And
@@ -52,6 +77,13 @@ const (
 	While
 	Break
 	Continue
+	Match
+	Case
+	Const
+	Yield
+	In
+	Spawn
+	Await
 
 	EOF
 )
@@ -0,0 +1,227 @@
+package glox
+
+var _ Visitor = (*walker)(nil)
+
+// Walk traverses the program in source order, calling fn for every
+// statement and expression. If fn returns false, the children of that
+// node are skipped.
+func Walk(program Program, fn func(node any) bool) {
+	w := &walker{fn}
+	w.walkStmts(program)
+}
+
+type walker struct {
+	fn func(node any) bool
+}
+
+func (w *walker) walkStmts(stmts []Stmt) {
+	for _, stmt := range stmts {
+		w.walkStmt(stmt)
+	}
+}
+
+func (w *walker) walkStmt(stmt Stmt) {
+	if stmt != nil && w.fn(stmt) {
+		_ = stmt.Accept(w)
+	}
+}
+
+func (w *walker) walkExpr(expr Expr) {
+	if expr != nil && w.fn(expr) {
+		_, _ = expr.Accept(w)
+	}
+}
+
+func (w *walker) walkParams(params ParamList) {
+	for _, value := range params.Defaults {
+		w.walkExpr(value)
+	}
+}
+
+func (w *walker) VisitBinaryExpr(expr *BinaryExpr) (any, error) {
+	w.walkExpr(expr.Left)
+	w.walkExpr(expr.Right)
+	return nil, nil
+}
+
+func (w *walker) VisitGroupingExpr(expr *GroupingExpr) (any, error) {
+	w.walkExpr(expr.Expression)
+	return nil, nil
+}
+
+func (w *walker) VisitLiteralExpr(*LiteralExpr) (any, error) {
+	return nil, nil
+}
+
+func (w *walker) VisitUnaryExpr(expr *UnaryExpr) (any, error) {
+	w.walkExpr(expr.Right)
+	return nil, nil
+}
+
+func (w *walker) VisitVariableExpr(*VariableExpr) (any, error) {
+	return nil, nil
+}
+
+func (w *walker) VisitAssignExpr(expr *AssignExpr) (any, error) {
+	w.walkExpr(expr.Value)
+	return nil, nil
+}
+
+func (w *walker) VisitCompoundAssignExpr(expr *CompoundAssignExpr) (any, error) {
+	w.walkExpr(expr.Value)
+	return nil, nil
+}
+
+func (w *walker) VisitIncrementExpr(*IncrementExpr) (any, error) {
+	return nil, nil
+}
+
+func (w *walker) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	w.walkExpr(expr.Condition)
+	w.walkExpr(expr.Then)
+	w.walkExpr(expr.Else)
+	return nil, nil
+}
+
+func (w *walker) VisitLogicalExpr(expr *LogicalExpr) (any, error) {
+	w.walkExpr(expr.Left)
+	w.walkExpr(expr.Right)
+	return nil, nil
+}
+
+func (w *walker) VisitCallExpr(expr *CallExpr) (any, error) {
+	w.walkExpr(expr.Callee)
+	for _, arg := range expr.Arguments {
+		w.walkExpr(arg)
+	}
+	for _, arg := range expr.Named {
+		w.walkExpr(arg.Value)
+	}
+	return nil, nil
+}
+
+func (w *walker) VisitSpreadExpr(expr *SpreadExpr) (any, error) {
+	w.walkExpr(expr.Value)
+	return nil, nil
+}
+
+func (w *walker) VisitAwaitExpr(expr *AwaitExpr) (any, error) {
+	w.walkExpr(expr.Value)
+	return nil, nil
+}
+
+func (w *walker) VisitLambdaExpr(expr *LambdaExpr) (any, error) {
+	w.walkParams(expr.ParamList)
+	w.walkStmts(expr.Body)
+	return nil, nil
+}
+
+func (w *walker) VisitListExpr(expr *ListExpr) (any, error) {
+	for _, element := range expr.Elements {
+		w.walkExpr(element)
+	}
+	return nil, nil
+}
+
+func (w *walker) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	w.walkExpr(expr.Object)
+	w.walkExpr(expr.Index)
+	return nil, nil
+}
+
+func (w *walker) VisitSetIndexExpr(expr *SetIndexExpr) (any, error) {
+	w.walkExpr(expr.Object)
+	w.walkExpr(expr.Index)
+	w.walkExpr(expr.Value)
+	return nil, nil
+}
+
+func (w *walker) VisitGetExpr(expr *GetExpr) (any, error) {
+	w.walkExpr(expr.Object)
+	return nil, nil
+}
+
+func (w *walker) VisitInterpolationExpr(expr *InterpolationExpr) (any, error) {
+	for _, part := range expr.Parts {
+		w.walkExpr(part)
+	}
+	return nil, nil
+}
+
+func (w *walker) VisitExpressionStmt(stmt *ExpressionStmt) error {
+	w.walkExpr(stmt.Expr)
+	return nil
+}
+
+func (w *walker) VisitPrintStmt(stmt *PrintStmt) error {
+	w.walkExpr(stmt.Expr)
+	return nil
+}
+
+func (w *walker) VisitVarDeclStmt(stmt *VarDeclStmt) error {
+	w.walkExpr(stmt.Initializer)
+	return nil
+}
+
+func (w *walker) VisitBlockStmt(stmt *BlockStmt) error {
+	w.walkStmts(stmt.Statements)
+	return nil
+}
+
+func (w *walker) VisitIfStmt(stmt *IfStmt) error {
+	w.walkExpr(stmt.Condition)
+	w.walkStmt(stmt.ThenBranch)
+	w.walkStmt(stmt.ElseBranch)
+	return nil
+}
+
+func (w *walker) VisitWhileStmt(stmt *WhileStmt) error {
+	w.walkExpr(stmt.Condition)
+	w.walkStmt(stmt.Body)
+	w.walkExpr(stmt.Increment)
+	return nil
+}
+
+func (w *walker) VisitBreakStmt(*BreakStmt) error {
+	return nil
+}
+
+func (w *walker) VisitContinueStmt(*ContinueStmt) error {
+	return nil
+}
+
+func (w *walker) VisitFunctionStmt(stmt *FunctionStmt) error {
+	w.walkParams(stmt.ParamList)
+	w.walkStmts(stmt.Body)
+	return nil
+}
+
+func (w *walker) VisitReturnStmt(stmt *ReturnStmt) error {
+	w.walkExpr(stmt.Value)
+	return nil
+}
+
+func (w *walker) VisitMatchStmt(stmt *MatchStmt) error {
+	w.walkExpr(stmt.Subject)
+	for _, arm := range stmt.Arms {
+		w.walkExpr(arm.Guard)
+		w.walkStmt(arm.Body)
+	}
+	return nil
+}
+
+func (w *walker) VisitYieldStmt(stmt *YieldStmt) error {
+	w.walkExpr(stmt.Value)
+	return nil
+}
+
+func (w *walker) VisitForInStmt(stmt *ForInStmt) error {
+	w.walkExpr(stmt.Iterable)
+	w.walkStmt(stmt.Body)
+	return nil
+}
+
+func (w *walker) VisitSpawnStmt(stmt *SpawnStmt) error {
+	w.walkExpr(stmt.Call)
+	return nil
+}